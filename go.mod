@@ -6,5 +6,5 @@ require github.com/tabbed/sqlc-go v1.16.0
 
 require (
 	github.com/jinzhu/inflection v1.0.0
-	google.golang.org/protobuf v1.28.1 // indirect
+	google.golang.org/protobuf v1.28.1
 )
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CheckADOEnums reports the enum parameters and result columns of q. The
+// generated enums are mapped to their database types by Npgsql only, so
+// the ado driver cannot bind or read them without emit_npgsql_features.
+func CheckADOEnums(q Query) error {
+	return checkEnums(q, true, "which the ado driver only maps with emit_npgsql_features")
+}
+
+// checkEnums reports the enum parameters of q, and its enum result columns
+// if columns is set, explaining why with reason.
+func checkEnums(q Query, columns bool, reason string) error {
+	var errs []error
+	check := func(what, name string, dbType DBType) {
+		if dbType.IsEnum {
+			errs = append(errs, fmt.Errorf("%s %s has the enum type %s, %s", what, name, dbType.Name, reason))
+		}
+	}
+	checkValue := func(what string, v QueryValue) {
+		if !v.IsClass() {
+			check(what, v.DBName, v.DBType)
+			return
+		}
+		for _, m := range v.Class.UniqueMembers() {
+			if m.Embed == nil {
+				check(what, m.DBName, m.DBType)
+				continue
+			}
+			for _, embedded := range m.Embed.Members {
+				check(what, m.DBName+"."+embedded.DBName, embedded.DBType)
+			}
+		}
+	}
+
+	checkValue("parameter", q.Arg)
+	if columns {
+		checkValue("column", q.Ret)
+	}
+	if len(errs) > 0 {
+		return errors.Join(prefixErrors(fmt.Sprintf("query %s in %s", q.MethodName, q.SourceName), errors.Join(errs...))...)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckADOEnums(t *testing.T) {
+	status := DBType{Name: "user_status", IsEnum: true}
+	user := &Class{Name: "User", Members: []ClassMember{
+		{Name: "ID", DBName: "id", DBType: DBType{Name: "int4"}},
+		{Name: "Status", DBName: "status", DBType: status},
+	}}
+
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{
+			name: "no enums",
+			q:    Query{Arg: QueryValue{DBName: "id", DBType: DBType{Name: "int4"}}},
+		},
+		{
+			name: "parameter",
+			q:    Query{Arg: QueryValue{DBName: "status", DBType: status}},
+			want: []string{"query GetUser in users.sql: parameter status has the enum type user_status"},
+		},
+		{
+			name: "params class",
+			q:    Query{Arg: QueryValue{Class: user}},
+			want: []string{"parameter status has the enum type"},
+		},
+		{
+			name: "embedded column",
+			q: Query{Ret: QueryValue{Class: &Class{Members: []ClassMember{
+				{Name: "ID", DBName: "id"},
+				{Name: "User", DBName: "users", Embed: user},
+			}}}},
+			want: []string{"column users.status has the enum type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.q.MethodName, tt.q.SourceName = "GetUser", "users.sql"
+			err := CheckADOEnums(tt.q)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("CheckADOEnums() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("CheckADOEnums() succeeded, want an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("CheckADOEnums() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Annotations are the options of a query given in its comments, such as
+//
+//	-- name: ListReports :many
+//	-- @timeout 300
+//	-- @prepare
+type Annotations struct {
+	// Timeout is the command timeout in seconds, nil for the default.
+	Timeout *int
+	// Prepare is set when the command is prepared before it runs.
+	Prepare bool
+}
+
+// parseAnnotations splits the annotations off the comments of a query and
+// returns them along with the remaining comments. Each annotation may be
+// given once.
+func parseAnnotations(comments []string) (Annotations, []string, error) {
+	var a Annotations
+	var rest []string
+	seen := map[string]struct{}{}
+
+	for _, comment := range comments {
+		line := strings.TrimSpace(comment)
+		if !strings.HasPrefix(line, "@") {
+			rest = append(rest, comment)
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if _, found := seen[fields[0]]; found {
+			return a, nil, fmt.Errorf("duplicate %s annotation", fields[0])
+		}
+		switch fields[0] {
+		case "@timeout":
+			if len(fields) != 2 {
+				return a, nil, fmt.Errorf("@timeout takes a number of seconds, got %q", line)
+			}
+			seconds, err := strconv.Atoi(fields[1])
+			if err != nil || seconds < 0 {
+				return a, nil, fmt.Errorf("invalid @timeout %q", fields[1])
+			}
+			a.Timeout = &seconds
+			seen[fields[0]] = struct{}{}
+		case "@prepare":
+			if len(fields) != 1 {
+				return a, nil, fmt.Errorf("@prepare takes no arguments, got %q", line)
+			}
+			a.Prepare = true
+			seen[fields[0]] = struct{}{}
+		default:
+			// Not ours, keep it as documentation
+			rest = append(rest, comment)
+		}
+	}
+
+	return a, rest, nil
+}
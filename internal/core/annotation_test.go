@@ -0,0 +1,89 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	seconds := func(n int) *int { return &n }
+	tests := []struct {
+		name     string
+		comments []string
+		want     Annotations
+		rest     []string
+		wantErr  string
+	}{
+		{
+			name:     "no annotations",
+			comments: []string{" Lists the users."},
+			rest:     []string{" Lists the users."},
+		},
+		{
+			name:     "timeout and prepare",
+			comments: []string{" Lists the users.", " @timeout 300", " @prepare"},
+			want:     Annotations{Timeout: seconds(300), Prepare: true},
+			rest:     []string{" Lists the users."},
+		},
+		{
+			name:     "no timeout",
+			comments: []string{"@timeout 0"},
+			want:     Annotations{Timeout: seconds(0)},
+		},
+		{
+			name:     "unknown annotation",
+			comments: []string{" @deprecated use ListUsers"},
+			rest:     []string{" @deprecated use ListUsers"},
+		},
+		{
+			name:     "negative timeout",
+			comments: []string{" @timeout -1"},
+			wantErr:  `invalid @timeout "-1"`,
+		},
+		{
+			name:     "non-numeric timeout",
+			comments: []string{" @timeout 5s"},
+			wantErr:  `invalid @timeout "5s"`,
+		},
+		{
+			name:     "timeout without seconds",
+			comments: []string{" @timeout"},
+			wantErr:  `@timeout takes a number of seconds, got "@timeout"`,
+		},
+		{
+			name:     "prepare with an argument",
+			comments: []string{" @prepare always"},
+			wantErr:  `@prepare takes no arguments, got "@prepare always"`,
+		},
+		{
+			name:     "duplicate timeout",
+			comments: []string{" @timeout 30", " @timeout 60"},
+			wantErr:  "duplicate @timeout annotation",
+		},
+		{
+			name:     "duplicate prepare",
+			comments: []string{" @prepare", " @prepare"},
+			wantErr:  "duplicate @prepare annotation",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := parseAnnotations(tt.comments)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseAnnotations() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAnnotations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAnnotations() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(rest, tt.rest) {
+				t.Errorf("parseAnnotations() comments = %q, want %q", rest, tt.rest)
+			}
+		})
+	}
+}
@@ -1,20 +1,27 @@
 package core
 
 import (
-	"strings"
-	"unicode"
-	"unicode/utf8"
-
 	plugin "github.com/tabbed/sqlc-go/codegen"
 )
 
 type ClassMember struct {
 	Name    string
+	ArgName string
 	DBName  string
 	Type    string
 	Comment string
 	NotNull bool
 	Column  *plugin.Column
+	DBType  DBType
+
+	// Ordinal is the position of the member's first column in a result row.
+	Ordinal int
+	// Embed is the model class of a table included with sqlc.embed(), in
+	// which case the member spans one ordinal per embedded column.
+	Embed *Class
+	// Optional is set on an embedded member whose row may be absent, as
+	// when its table is on the outer side of a join.
+	Optional bool
 }
 
 type Class struct {
@@ -24,24 +31,27 @@ type Class struct {
 	Comment string
 }
 
-func ClassName(name string, settings *plugin.Settings) string {
-	if rename := settings.Rename[name]; rename != "" {
-		return rename
-	}
+// UniqueMembers returns the members with distinct names, in order. Params
+// classes repeat a member for every use of a named parameter.
+func (c Class) UniqueMembers() []ClassMember {
+	seen := map[string]struct{}{}
+	members := make([]ClassMember, 0, len(c.Members))
 
-	out := ""
-	for _, p := range strings.Split(name, "_") {
-		if p == "id" {
-			out += "ID"
-		} else {
-			out += strings.Title(p)
+	for _, member := range c.Members {
+		if _, found := seen[member.Name]; found {
+			continue
 		}
+		seen[member.Name] = struct{}{}
+		members = append(members, member)
 	}
 
-	r, _ := utf8.DecodeRuneInString(out)
-	if unicode.IsDigit(r) {
-		return "_" + out
-	} else {
-		return out
+	return members
+}
+
+func ClassName(name string, settings *plugin.Settings, conf *Config) string {
+	if rename := settings.GetRename()[name]; rename != "" {
+		return SanitizeIdentifier(rename)
 	}
+
+	return PascalCase(name, conf)
 }
@@ -1,11 +1,305 @@
 package core
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 type Config struct {
-	Namespace                   string   `json:"namespace"`
-	QueryParamLimit             int      `json:"query_param_limit"`
-	EmitAsync                   bool     `json:"emit_async"`
-	EmitNullOperators           bool     `json:"emit_null_ops"`
-	LogFile                     string   `json:"log_file"`
-	EmitExactTableNames         bool     `json:"emit_exact_table_names"`
-	InflectionExcludeTableNames []string `json:"inflection_exclude_table_names"`
+	Namespace                   string            `json:"namespace"`
+	QueryParamLimit             int               `json:"query_param_limit"`
+	Driver                      string            `json:"driver"`
+	EmitNpgsqlFeatures          bool              `json:"emit_npgsql_features"`
+	NpgsqlVersion               string            `json:"npgsql_version"`
+	EmitAsync                   bool              `json:"emit_async"`
+	EmitSync                    bool              `json:"emit_sync"`
+	EmitConfigureAwait          bool              `json:"emit_configure_await"`
+	EmitValueTask               bool              `json:"emit_value_task"`
+	EmitNullOperators           bool              `json:"emit_null_ops"`
+	LogFile                     string            `json:"log_file"`
+	EmitExactTableNames         bool              `json:"emit_exact_table_names"`
+	InflectionExcludeTableNames []string          `json:"inflection_exclude_table_names"`
+	ResultClassReuse            string            `json:"result_class_reuse"`
+	EmitSharedClasses           bool              `json:"emit_shared_classes"`
+	CsharpVersion               int               `json:"csharp_version"`
+	NamingStyle                 string            `json:"naming_style"`
+	Initialisms                 []string          `json:"initialisms"`
+	OutputLayout                string            `json:"output_layout"`
+	ModelsFolder                string            `json:"models_folder"`
+	QueriesFolder               string            `json:"queries_folder"`
+	UnnestQueryClasses          bool              `json:"unnest_query_classes"`
+	ModelsFileName              string            `json:"models_file_name"`
+	HelpersFileName             string            `json:"helpers_file_name"`
+	QueryClassName              string            `json:"query_class_name"`
+	EmitProjectFile             bool              `json:"emit_project_file"`
+	ProjectName                 string            `json:"project_name"`
+	TargetFramework             string            `json:"target_framework"`
+	PackageVersions             map[string]string `json:"package_versions"`
+	EmitSchemaMetadata          bool              `json:"emit_schema_metadata"`
+	EmitQueryRegistry           bool              `json:"emit_query_registry"`
+	EmitInterface               bool              `json:"emit_interface"`
+	EmitFakes                   bool              `json:"emit_fakes"`
+	EmitTracing                 bool              `json:"emit_tracing"`
+	EmitTracingStatement        bool              `json:"emit_tracing_statement"`
+	EmitDiagnostics             bool              `json:"emit_diagnostics"`
+	CommandTimeout              *int              `json:"command_timeout"`
+	EmitPrepared                bool              `json:"emit_prepared"`
+	EmitDependencyInjection     bool              `json:"emit_dependency_injection"`
+}
+
+// Values for the output_layout option.
+const (
+	// LayoutCombined writes all models into one file and the queries of
+	// each SQL file into one file. This is the default.
+	LayoutCombined = "combined"
+	// LayoutPerType writes every enum, model and query class into a file
+	// of its own.
+	LayoutPerType = "per_type"
+)
+
+// Values for the driver option.
+const (
+	// DriverNpgsql runs the queries with Npgsql commands. This is the default.
+	DriverNpgsql = "npgsql"
+	// DriverDapper runs the queries with Dapper on any connection.
+	DriverDapper = "dapper"
+	// DriverADO runs the queries with the System.Data.Common classes, so any
+	// DbConnection, wrapped or faked, will do.
+	DriverADO = "ado"
+)
+
+// defaultTargetFramework is the target_framework of the project file unless
+// set.
+const defaultTargetFramework = "net8.0"
+
+// minCsharpVersion is the oldest C# version the generated code compiles
+// with, as it uses file-scoped namespaces. It is the default csharp_version.
+const minCsharpVersion = 10
+
+// defaultConfig returns the options with their documented defaults, which
+// the plugin options are decoded over.
+func defaultConfig() Config {
+	return Config{
+		QueryParamLimit:  1,
+		Driver:           DriverNpgsql,
+		ResultClassReuse: ReuseStrict,
+		NamingStyle:      NamingInitialisms,
+		Initialisms:      append([]string(nil), defaultInitialisms...),
+		OutputLayout:     LayoutCombined,
+		ModelsFileName:   "Models",
+		HelpersFileName:  "DbHelper",
+		QueryClassName:   "{{.File}}",
+		CsharpVersion:    minCsharpVersion,
+		TargetFramework:  defaultTargetFramework,
+	}
+}
+
+// ParseConfig decodes the plugin options over the defaults and validates
+// them. Unknown options are rejected, so a misspelt option is not silently
+// ignored.
+func ParseConfig(options []byte) (Config, error) {
+	conf := defaultConfig()
+	if len(bytes.TrimSpace(options)) > 0 {
+		if err := checkOptionNames(options); err != nil {
+			return conf, err
+		}
+		if err := json.Unmarshal(options, &conf); err != nil {
+			return conf, decodeError(err)
+		}
+	}
+	if conf.ProjectName == "" {
+		conf.ProjectName = conf.Namespace
+	}
+	return conf, conf.Validate()
+}
+
+// optionNames are the names of the options, the json tags of Config.
+var optionNames = func() map[string]struct{} {
+	names := map[string]struct{}{}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		names[name] = struct{}{}
+	}
+	return names
+}()
+
+// checkOptionNames reports every option that is not one of optionNames.
+// encoding/json matches the names regardless of case, so even with
+// DisallowUnknownFields "Driver" would set driver, and it stops at the
+// first unknown name. The names are checked on the raw keys beforehand.
+func checkOptionNames(options []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(options, &raw); err != nil {
+		return decodeError(err)
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		if _, found := optionNames[key]; found {
+			continue
+		}
+		if name := foldedOptionName(key); name != "" {
+			errs = append(errs, fmt.Errorf("unknown option %q, did you mean %q?", key, name))
+		} else {
+			errs = append(errs, fmt.Errorf("unknown option %q", key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// foldedOptionName returns the option named key in another case, or "".
+func foldedOptionName(key string) string {
+	for name := range optionNames {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+	}
+	return ""
+}
+
+// decodeError rewords the errors of the JSON decoder to name the option
+// at fault.
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Errorf("invalid %s: expected %s, got %s", typeErr.Field, jsonKind(typeErr.Type), typeErr.Value)
+	}
+	return fmt.Errorf("invalid plugin options: %w", err)
+}
+
+// jsonKind describes the JSON value decoded into t.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonKind(t.Elem())
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "a list of " + strings.TrimPrefix(jsonKind(t.Elem()), "a ") + "s"
+	case reflect.Map:
+		return "an object of " + strings.TrimPrefix(jsonKind(t.Elem()), "a ") + "s"
+	default:
+		return t.String()
+	}
+}
+
+// Validate reports every option holding a value the plugin cannot generate
+// code for.
+func (c Config) Validate() error {
+	var errs []error
+	if err := validateNamespace(c.Namespace); err != nil {
+		errs = append(errs, err)
+	}
+	if c.QueryParamLimit < 1 && c.QueryParamLimit != -1 {
+		errs = append(errs, fmt.Errorf("invalid query_param_limit %d: expected a positive number, or -1 for no limit", c.QueryParamLimit))
+	}
+
+	switch c.Driver {
+	case DriverNpgsql, DriverDapper, DriverADO:
+	default:
+		errs = append(errs, fmt.Errorf("invalid driver %q: expected %q, %q or %q", c.Driver, DriverNpgsql, DriverDapper, DriverADO))
+	}
+	if _, err := npgsqlMajor(c.NpgsqlVersion); err != nil {
+		errs = append(errs, err)
+	}
+	if c.EmitDependencyInjection {
+		switch {
+		case c.Driver != DriverNpgsql:
+			errs = append(errs, fmt.Errorf("emit_dependency_injection requires the %s driver", DriverNpgsql))
+		case c.LegacyNpgsql():
+			errs = append(errs, fmt.Errorf("emit_dependency_injection requires npgsql_version 7 or later, got %q", c.NpgsqlVersion))
+		}
+	}
+
+	switch c.ResultClassReuse {
+	case ReuseStrict, ReuseNever:
+	default:
+		errs = append(errs, fmt.Errorf("invalid result_class_reuse %q: expected %q or %q", c.ResultClassReuse, ReuseStrict, ReuseNever))
+	}
+	switch c.NamingStyle {
+	case NamingInitialisms, NamingDotnet:
+	default:
+		errs = append(errs, fmt.Errorf("invalid naming_style %q: expected %q or %q", c.NamingStyle, NamingInitialisms, NamingDotnet))
+	}
+	switch c.OutputLayout {
+	case LayoutCombined, LayoutPerType:
+	default:
+		errs = append(errs, fmt.Errorf("invalid output_layout %q: expected %q or %q", c.OutputLayout, LayoutCombined, LayoutPerType))
+	}
+
+	if c.CsharpVersion < minCsharpVersion {
+		errs = append(errs, fmt.Errorf("invalid csharp_version %d: the generated code needs C# %d or later", c.CsharpVersion, minCsharpVersion))
+	}
+	if c.ModelsFileName == "" {
+		errs = append(errs, fmt.Errorf("invalid models_file_name: must not be empty"))
+	}
+	if c.HelpersFileName == "" {
+		errs = append(errs, fmt.Errorf("invalid helpers_file_name: must not be empty"))
+	}
+	if c.TargetFramework == "" {
+		errs = append(errs, fmt.Errorf("invalid target_framework: must not be empty"))
+	}
+	if c.CommandTimeout != nil && *c.CommandTimeout < 0 {
+		errs = append(errs, fmt.Errorf("invalid command_timeout %d: expected a number of seconds, or 0 for none", *c.CommandTimeout))
+	}
+	return errors.Join(errs...)
+}
+
+// validateNamespace checks that name is a legal C# namespace: identifiers
+// separated by dots.
+func validateNamespace(name string) error {
+	if name == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	for _, part := range strings.Split(name, ".") {
+		if !IsIdentifier(part) {
+			return fmt.Errorf("invalid namespace %q: %q is not a C# identifier", name, part)
+		}
+	}
+	return nil
+}
+
+// EmitsInterface reports whether the IQuerier interface and its Querier
+// implementation are generated, which the fakes and the dependency
+// injection extension build on.
+func (c Config) EmitsInterface() bool {
+	return c.EmitInterface || c.EmitFakes || c.EmitDependencyInjection
+}
+
+// LegacyNpgsql reports whether npgsql_version names a release before
+// Npgsql 7, which introduced NpgsqlDataSource.
+func (c Config) LegacyNpgsql() bool {
+	major, _ := npgsqlMajor(c.NpgsqlVersion)
+	return major != 0 && major < 7
+}
+
+// npgsqlMajor returns the major version of the npgsql_version option, or 0
+// when it is not set.
+func npgsqlMajor(version string) (int, error) {
+	if version == "" {
+		return 0, nil
+	}
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid npgsql_version %q: expected a version such as \"6.0.11\"", version)
+	}
+	return n, nil
 }
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfigCsharpVersion(t *testing.T) {
+	tests := []struct {
+		options string
+		want    int
+		wantErr string
+	}{
+		{options: `{"namespace": "App.Db"}`, want: 10},
+		{options: `{"namespace": "App.Db", "csharp_version": 12}`, want: 12},
+		{options: `{"namespace": "App.Db", "csharp_version": 9}`, wantErr: "invalid csharp_version 9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.options, func(t *testing.T) {
+			conf, err := ParseConfig([]byte(tt.options))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			if conf.CsharpVersion != tt.want {
+				t.Errorf("CsharpVersion = %d, want %d", conf.CsharpVersion, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		wantErr string
+	}{
+		{
+			name:    "unknown option",
+			options: `{"namespace": "App.Db", "emit_nul_ops": true}`,
+			wantErr: `unknown option "emit_nul_ops"`,
+		},
+		{
+			name:    "wrong case",
+			options: `{"namespace": "App.Db", "Driver": "dapper"}`,
+			wantErr: `unknown option "Driver", did you mean "driver"?`,
+		},
+		{
+			name:    "wrong type",
+			options: `{"namespace": "App.Db", "emit_async": "yes"}`,
+			wantErr: "invalid emit_async: expected a boolean, got string",
+		},
+		{
+			name:    "no query parameters inlined",
+			options: `{"namespace": "App.Db", "query_param_limit": 0}`,
+			wantErr: "invalid query_param_limit 0: expected a positive number, or -1 for no limit",
+		},
+		{
+			name:    "namespace starting with a digit",
+			options: `{"namespace": "1App.Db"}`,
+			wantErr: `invalid namespace "1App.Db": "1App" is not a C# identifier`,
+		},
+		{
+			name:    "namespace with an empty part",
+			options: `{"namespace": "App..Db"}`,
+			wantErr: `invalid namespace "App..Db": "" is not a C# identifier`,
+		},
+		{
+			name:    "missing namespace",
+			options: `{"query_param_limit": -1}`,
+			wantErr: "namespace is required",
+		},
+		{
+			name:    "not an object",
+			options: `["namespace"]`,
+			wantErr: "invalid plugin options",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.options))
+			if err == nil || err.Error() != tt.wantErr && !strings.HasPrefix(err.Error(), tt.wantErr+":") {
+				t.Fatalf("ParseConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// UseNamedParameters rewrites the $1, $2, ... parameters in the SQL of q to
+// @name parameters, named as the members of the value Dapper binds them
+// from. Comments, quoted strings and identifiers are left untouched. It
+// fails for the results Dapper cannot read, see checkDapperColumns, and
+// for enum parameters: Dapper binds enums as their underlying integers
+// without consulting the type handlers.
+func UseNamedParameters(q *Query) error {
+	if hasEmbeddedMembers(q.Ret) {
+		return fmt.Errorf("query %s in %s: sqlc.embed is not supported by the dapper driver", q.MethodName, q.SourceName)
+	}
+	if err := checkDapperColumns(q.Ret); err != nil {
+		return errors.Join(prefixErrors(fmt.Sprintf("query %s in %s", q.MethodName, q.SourceName), err)...)
+	}
+	if err := checkEnums(*q, false, "which Dapper binds as an integer; cast a text parameter to the enum in the SQL instead"); err != nil {
+		return err
+	}
+
+	names := q.Arg.BindNames()
+	var b strings.Builder
+	sql := q.SQL
+	for i := 0; i < len(sql); {
+		inWord := i > 0 && isWordByte(sql[i-1])
+		if n := skippedLen(sql[i:], inWord); n > 0 {
+			b.WriteString(sql[i : i+n])
+			i += n
+			continue
+		}
+
+		if sql[i] != '$' || inWord || i+1 == len(sql) || !isDigit(sql[i+1]) {
+			b.WriteByte(sql[i])
+			i++
+			continue
+		}
+		j := i + 1
+		n := 0
+		for j < len(sql) && isDigit(sql[j]) {
+			n = n*10 + int(sql[j]-'0')
+			j++
+		}
+		if n < 1 || n > len(names) {
+			return fmt.Errorf("query %s in %s: no parameter for $%d", q.MethodName, q.SourceName, n)
+		}
+		b.WriteString("@" + names[n-1])
+		i = j
+	}
+
+	q.SQL = b.String()
+	return nil
+}
+
+// skippedLen returns the length of the comment, string constant, quoted
+// identifier or dollar-quoted body sql starts with, or 0 if it starts with
+// none. inWord is set when sql continues a word, in which E' and $ are part
+// of the word rather than the start of a string.
+func skippedLen(sql string, inWord bool) int {
+	switch {
+	case strings.HasPrefix(sql, "--"):
+		if end := strings.IndexByte(sql, '\n'); end >= 0 {
+			return end
+		}
+		return len(sql)
+	case strings.HasPrefix(sql, "/*"):
+		return blockCommentLen(sql)
+	case sql[0] == '\'' || sql[0] == '"':
+		return quotedLen(sql, false)
+	case inWord:
+		return 0
+	case (sql[0] == 'E' || sql[0] == 'e') && strings.HasPrefix(sql[1:], "'"):
+		return 1 + quotedLen(sql[1:], true)
+	case sql[0] == '$':
+		return dollarQuotedLen(sql)
+	default:
+		return 0
+	}
+}
+
+// blockCommentLen returns the length of the block comment sql starts with,
+// which may nest other block comments.
+func blockCommentLen(sql string) int {
+	depth := 0
+	for i := 0; i+1 < len(sql); i++ {
+		switch sql[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+// quotedLen returns the length of the string or identifier sql starts
+// with, quoted by its first byte. A doubled quote stands for the quote
+// itself, as does a backslash escaped one in escape strings.
+func quotedLen(sql string, backslashes bool) int {
+	quote := sql[0]
+	for i := 1; i < len(sql); i++ {
+		switch {
+		case backslashes && sql[i] == '\\':
+			i++
+		case sql[i] == quote && i+1 < len(sql) && sql[i+1] == quote:
+			i++
+		case sql[i] == quote:
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// dollarQuotedLen returns the length of the dollar-quoted body sql starts
+// with, as in $$text$$ or $tag$text$tag$, or 0 if sql does not start with
+// a tag. Tags do not start with a digit, so $1 is a parameter.
+func dollarQuotedLen(sql string) int {
+	end := strings.IndexByte(sql[1:], '$')
+	if end < 0 {
+		return 0
+	}
+	tag := sql[1 : end+1]
+	if tag != "" && isDigit(tag[0]) {
+		return 0
+	}
+	for i := 0; i < len(tag); i++ {
+		if !isWordByte(tag[i]) || tag[i] == '$' {
+			return 0
+		}
+	}
+
+	delim := sql[:end+2]
+	body := strings.Index(sql[len(delim):], delim)
+	if body < 0 {
+		return len(sql)
+	}
+	return 2*len(delim) + body
+}
+
+// BindNames returns the names Dapper binds the parameters $1, $2, ... by:
+// the members of an emitted Params class, or else the method parameters.
+// A parameter used repeatedly is named once for every use.
+func (v QueryValue) BindNames() []string {
+	if v.isEmpty() {
+		return nil
+	}
+	if !v.IsClass() {
+		return []string{strings.TrimPrefix(v.Name, "@")}
+	}
+
+	names := make([]string, 0, len(v.Class.Members))
+	for _, m := range v.Class.Members {
+		name := m.ArgName
+		if v.EmitClass() {
+			name = m.Name
+		}
+		names = append(names, strings.TrimPrefix(name, "@"))
+	}
+	return names
+}
+
+// BindArgs returns the C# expression of the object Dapper binds the
+// parameters from.
+func (v QueryValue) BindArgs() string {
+	switch {
+	case v.isEmpty():
+		return "null"
+	case v.EmitClass():
+		return v.Name
+	case !v.IsClass():
+		return "new { " + v.Name + " }"
+	}
+
+	var names []string
+	for _, m := range v.UniqueMembers() {
+		names = append(names, m.ArgName)
+	}
+	return "new { " + strings.Join(names, ", ") + " }"
+}
+
+// checkDapperColumns reports the result columns Dapper would leave unread.
+// Dapper sets the members of a class by the names of the columns, ignoring
+// case and underscores, so a member renamed, sanitized or suffixed to
+// tell apart columns of the same name, or named after an unnamed column,
+// would keep its default value.
+func checkDapperColumns(v QueryValue) error {
+	if !v.IsClass() {
+		return nil
+	}
+
+	var errs []error
+	for i, m := range v.Class.Members {
+		var name string
+		if m.Column != nil {
+			name = m.Column.Name
+		}
+		switch {
+		case name == "":
+			errs = append(errs, fmt.Errorf("column %d has no name for Dapper to map it to %s by, name it with AS", i+1, m.Name))
+		case !dapperMatches(name, m.Name):
+			errs = append(errs, fmt.Errorf("column %s cannot be mapped to %s by Dapper, which matches members by name; alias it with AS to a name unique in the query", name, m.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dapperMatches reports whether Dapper maps the column onto the member, as
+// set up by RegisterDapperTypeHandlers with MatchNamesWithUnderscores.
+func dapperMatches(column, member string) bool {
+	return strings.EqualFold(column, member) || strings.EqualFold(strings.ReplaceAll(column, "_", ""), member)
+}
+
+func hasEmbeddedMembers(v QueryValue) bool {
+	if !v.IsClass() {
+		return false
+	}
+	for _, m := range v.Class.Members {
+		if m.Embed != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isWordByte reports whether c may be part of an unquoted identifier or
+// keyword. Bytes of multibyte UTF-8 letters all qualify.
+func isWordByte(c byte) bool {
+	return isDigit(c) || c == '_' || c == '$' || c >= 0x80 ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
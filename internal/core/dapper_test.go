@@ -0,0 +1,189 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+)
+
+func TestUseNamedParameters(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "parameters",
+			sql:  "SELECT id FROM users WHERE name = $1 AND email = $2",
+			want: "SELECT id FROM users WHERE name = @name AND email = @email",
+		},
+		{
+			name: "line comment",
+			sql:  "SELECT id FROM users -- by $1\nWHERE name = $1",
+			want: "SELECT id FROM users -- by $1\nWHERE name = @name",
+		},
+		{
+			name: "block comment",
+			sql:  "SELECT id /* not $1 */ FROM users WHERE name = $1",
+			want: "SELECT id /* not $1 */ FROM users WHERE name = @name",
+		},
+		{
+			name: "nested block comment",
+			sql:  "SELECT id /* outer /* inner $1 */ still $2 */ FROM users WHERE name = $1",
+			want: "SELECT id /* outer /* inner $1 */ still $2 */ FROM users WHERE name = @name",
+		},
+		{
+			name: "string",
+			sql:  "SELECT id FROM users WHERE name = $1 AND email <> 'it''s $2'",
+			want: "SELECT id FROM users WHERE name = @name AND email <> 'it''s $2'",
+		},
+		{
+			name: "escape string",
+			sql:  `SELECT id FROM users WHERE email <> E'it\'s $1' AND name = $1`,
+			want: `SELECT id FROM users WHERE email <> E'it\'s $1' AND name = @name`,
+		},
+		{
+			name: "escape string with a backslash",
+			sql:  `SELECT id FROM users WHERE email <> e'\\' AND name = $1`,
+			want: `SELECT id FROM users WHERE email <> e'\\' AND name = @name`,
+		},
+		{
+			name: "backslash in a standard string",
+			sql:  `SELECT id FROM users WHERE email <> '\' AND name = $1`,
+			want: `SELECT id FROM users WHERE email <> '\' AND name = @name`,
+		},
+		{
+			name: "dollar-quoted body",
+			sql:  "SELECT id FROM users WHERE email <> $$it's $1$$ AND name = $1",
+			want: "SELECT id FROM users WHERE email <> $$it's $1$$ AND name = @name",
+		},
+		{
+			name: "tagged dollar-quoted body",
+			sql:  "SELECT id FROM users WHERE email <> $body$ $$ $1 $body$ AND name = $1",
+			want: "SELECT id FROM users WHERE email <> $body$ $$ $1 $body$ AND name = @name",
+		},
+		{
+			name: "quoted identifier",
+			sql:  `SELECT "col$1" FROM users WHERE "na""me$2" = $1`,
+			want: `SELECT "col$1" FROM users WHERE "na""me$2" = @name`,
+		},
+		{
+			name: "identifier containing a dollar",
+			sql:  "SELECT col$1 FROM users WHERE name = $1",
+			want: "SELECT col$1 FROM users WHERE name = @name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := Query{
+				MethodName: "ListUsers",
+				SourceName: "users.sql",
+				SQL:        tt.sql,
+				Arg: QueryValue{Name: "arg", Class: &Class{Members: []ClassMember{
+					{Name: "Name", ArgName: "name"},
+					{Name: "Email", ArgName: "email"},
+				}}},
+			}
+			if err := UseNamedParameters(&q); err != nil {
+				t.Fatalf("UseNamedParameters() error = %v", err)
+			}
+			if q.SQL != tt.want {
+				t.Errorf("UseNamedParameters() SQL = %q, want %q", q.SQL, tt.want)
+			}
+		})
+	}
+}
+
+func TestUseNamedParametersColumns(t *testing.T) {
+	column := func(name string) *plugin.Column {
+		return &plugin.Column{Name: name}
+	}
+	tests := []struct {
+		name    string
+		members []ClassMember
+		wantErr string
+	}{
+		{
+			name: "matching names",
+			members: []ClassMember{
+				{Name: "ID", Column: column("id")},
+				{Name: "UserID", Column: column("user_id")},
+				{Name: "CreatedAt", Column: column("createdAt")},
+			},
+		},
+		{
+			name: "suffixed duplicate",
+			members: []ClassMember{
+				{Name: "ID", Column: column("id")},
+				{Name: "ID_2", Column: column("id")},
+			},
+			wantErr: "column id cannot be mapped to ID_2",
+		},
+		{
+			name:    "renamed",
+			members: []ClassMember{{Name: "Identifier", Column: column("id")}},
+			wantErr: "column id cannot be mapped to Identifier",
+		},
+		{
+			name:    "sanitized",
+			members: []ClassMember{{Name: "ZipCode", Column: column("zip-code")}},
+			wantErr: "column zip-code cannot be mapped to ZipCode",
+		},
+		{
+			name:    "unnamed",
+			members: []ClassMember{{Name: "ID", Column: column("id")}, {Name: "Column2", Column: column("")}},
+			wantErr: "column 2 has no name for Dapper to map it to Column2 by",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := Query{
+				MethodName: "ListUsers",
+				SourceName: "users.sql",
+				SQL:        "SELECT 1",
+				Ret:        QueryValue{Emit: true, Name: "i", Class: &Class{Name: "ListUsersRow", Members: tt.members}},
+			}
+			err := UseNamedParameters(&q)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("UseNamedParameters() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("UseNamedParameters() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUseNamedParametersEnums(t *testing.T) {
+	status := DBType{Name: "user_status", IsEnum: true}
+	tests := []struct {
+		name    string
+		q       Query
+		wantErr string
+	}{
+		{
+			name:    "enum parameter",
+			q:       Query{SQL: "UPDATE users SET status = $1", Arg: QueryValue{Name: "status", DBName: "status", Typ: "UserStatus", DBType: status}},
+			wantErr: "query SetStatus in users.sql: parameter status has the enum type user_status, which Dapper binds as an integer",
+		},
+		{
+			name: "enum column",
+			q: Query{SQL: "SELECT status FROM users", Ret: QueryValue{Emit: true, Name: "i", Class: &Class{Name: "SetStatusRow", Members: []ClassMember{
+				{Name: "Status", Type: "UserStatus", DBType: status, Column: &plugin.Column{Name: "status"}},
+			}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.q.MethodName, tt.q.SourceName = "SetStatus", "users.sql"
+			err := UseNamedParameters(&tt.q)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("UseNamedParameters() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("UseNamedParameters() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"strings"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+	"github.com/tabbed/sqlc-go/sdk"
+)
+
+// npgsqlDbTypes are the NpgsqlDbType members of the Postgres types Npgsql
+// cannot tell apart by the CLR type of a parameter, such as a string for
+// jsonb or a DateTime for timestamp.
+var npgsqlDbTypes = map[string]string{
+	"json":        "Json",
+	"jsonb":       "Jsonb",
+	"xml":         "Xml",
+	"citext":      "Citext",
+	"inet":        "Inet",
+	"cidr":        "Cidr",
+	"macaddr":     "MacAddr",
+	"macaddr8":    "MacAddr8",
+	"date":        "Date",
+	"timestamp":   "Timestamp",
+	"timestamptz": "TimestampTz",
+	"time":        "Time",
+	"timetz":      "TimeTz",
+	"interval":    "Interval",
+	"money":       "Money",
+	"hstore":      "Hstore",
+	"ltree":       "LTree",
+	"lquery":      "LQuery",
+	"ltxtquery":   "LTxtQuery",
+}
+
+// DBType is the Postgres type of a parameter.
+type DBType struct {
+	// Name is the type name as written in the schema, e.g. pg_catalog.int4.
+	Name    string
+	IsArray bool
+	IsEnum  bool
+}
+
+func newDBType(req *plugin.CodeGenRequest, col *plugin.Column) DBType {
+	if col == nil || col.Type == nil {
+		return DBType{}
+	}
+	return DBType{
+		Name:    sdk.DataType(col.Type),
+		IsArray: col.IsArray,
+		IsEnum:  isEnum(req, col.Type),
+	}
+}
+
+func isEnum(req *plugin.CodeGenRequest, typ *plugin.Identifier) bool {
+	_, enum := findEnum(req, typ)
+	return enum != nil
+}
+
+// findEnum returns the catalog enum typ names along with its schema, or a
+// nil enum when typ is not one.
+func findEnum(req *plugin.CodeGenRequest, typ *plugin.Identifier) (string, *plugin.Enum) {
+	schemaName := typ.Schema
+	if schemaName == "" {
+		schemaName = req.Catalog.DefaultSchema
+	}
+	for _, schema := range req.Catalog.Schemas {
+		if schema.Name != schemaName {
+			continue
+		}
+		for _, enum := range schema.Enums {
+			if enum.Name == typ.Name {
+				return schema.Name, enum
+			}
+		}
+	}
+	return "", nil
+}
+
+// enumClassName returns the name of the C# enum generated for the catalog
+// enum typ, or "" when typ is not an enum.
+func enumClassName(req *plugin.CodeGenRequest, typ *plugin.Identifier, conf *Config) string {
+	schema, enum := findEnum(req, typ)
+	if enum == nil {
+		return ""
+	}
+	return ClassName(enumName(req, schema, enum), req.Settings, conf)
+}
+
+// enumName is the name the C# enum of a catalog enum is derived from,
+// prefixed with its schema outside the default one.
+func enumName(req *plugin.CodeGenRequest, schema string, enum *plugin.Enum) string {
+	if schema == req.Catalog.DefaultSchema {
+		return enum.Name
+	}
+	return schema + "_" + enum.Name
+}
+
+// Initializer returns the NpgsqlParameter property pinning the type, or ""
+// when Npgsql infers it correctly from the CLR type. Enums are named by
+// DataTypeName, the types in npgsqlDbTypes by NpgsqlDbType.
+func (t DBType) Initializer() string {
+	if t.IsEnum {
+		name := t.Name
+		if t.IsArray {
+			name += "[]"
+		}
+		return `DataTypeName = "` + name + `"`
+	}
+
+	member, found := npgsqlDbTypes[strings.TrimPrefix(t.Name, "pg_catalog.")]
+	if !found {
+		return ""
+	}
+	if t.IsArray {
+		return "NpgsqlDbType = NpgsqlTypes.NpgsqlDbType.Array | NpgsqlTypes.NpgsqlDbType." + member
+	}
+	return "NpgsqlDbType = NpgsqlTypes.NpgsqlDbType." + member
+}
@@ -0,0 +1,32 @@
+package core
+
+import "testing"
+
+func TestDBTypeInitializer(t *testing.T) {
+	tests := []struct {
+		name   string
+		dbType DBType
+		want   string
+	}{
+		{name: "enum", dbType: DBType{Name: "user_status", IsEnum: true}, want: `DataTypeName = "user_status"`},
+		{name: "enum of another schema", dbType: DBType{Name: "billing.plan", IsEnum: true}, want: `DataTypeName = "billing.plan"`},
+		{name: "enum array", dbType: DBType{Name: "user_status", IsArray: true, IsEnum: true}, want: `DataTypeName = "user_status[]"`},
+		{name: "jsonb", dbType: DBType{Name: "jsonb"}, want: "NpgsqlDbType = NpgsqlTypes.NpgsqlDbType.Jsonb"},
+		{name: "catalog type", dbType: DBType{Name: "pg_catalog.timestamptz"}, want: "NpgsqlDbType = NpgsqlTypes.NpgsqlDbType.TimestampTz"},
+		{
+			name:   "array",
+			dbType: DBType{Name: "pg_catalog.date", IsArray: true},
+			want:   "NpgsqlDbType = NpgsqlTypes.NpgsqlDbType.Array | NpgsqlTypes.NpgsqlDbType.Date",
+		},
+		{name: "inferred from the CLR type", dbType: DBType{Name: "pg_catalog.int4"}},
+		{name: "inferred array", dbType: DBType{Name: "text", IsArray: true}},
+		{name: "unknown type", dbType: DBType{Name: "geometry"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dbType.Initializer(); got != tt.want {
+				t.Errorf("Initializer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
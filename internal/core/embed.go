@@ -0,0 +1,83 @@
+package core
+
+import (
+	plugin "github.com/tabbed/sqlc-go/codegen"
+	sdk "github.com/tabbed/sqlc-go/sdk"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// embedTableField is the protobuf field number of Column.embed_table.
+// The plugin SDK we build against predates sqlc.embed, so the field
+// arrives as an unknown field and has to be decoded by hand.
+const embedTableField protowire.Number = 14
+
+// EmbedTable returns the table a column was embedded from using
+// sqlc.embed(), or nil for regular columns.
+func EmbedTable(col *plugin.Column) *plugin.Identifier {
+	b := col.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil
+		}
+		b = b[n:]
+
+		if num == embedTableField && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil
+			}
+			table := &plugin.Identifier{}
+			if err := table.UnmarshalVT(v); err != nil {
+				return nil
+			}
+			return table
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// embedOptional reports whether the row of the table embedded as embed may
+// be absent from the rows of a query, as on the outer side of a join. sqlc
+// reports the columns of such a table as nullable, so the row is optional
+// when every column of the table in the result is nullable, although some
+// are NOT NULL in the table. A table without NOT NULL columns never is, as
+// an absent row cannot be told from a row of NULLs.
+func embedOptional(req *plugin.CodeGenRequest, embed *Class, table *plugin.Identifier, columns []codeColumn) bool {
+	required := false
+	for _, m := range embed.Members {
+		if m.Column != nil && m.Column.NotNull {
+			required = true
+		}
+	}
+	if !required {
+		return false
+	}
+
+	for _, c := range columns {
+		from := c.Table
+		if embedded := EmbedTable(c.Column); embedded != nil {
+			from = embedded
+		}
+		if from != nil && c.NotNull && sdk.SameTableName(from, table, req.Catalog.DefaultSchema) {
+			return false
+		}
+	}
+	return true
+}
+
+func findClass(classes []Class, table *plugin.Identifier, defaultSchema string) *Class {
+	for i := range classes {
+		if sdk.SameTableName(table, classes[i].Table, defaultSchema) {
+			return &classes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"testing"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// embedColumn returns a column embedding table, carrying embed_table as
+// the unknown field the plugin SDK leaves it in, after another unknown
+// field.
+func embedColumn(name string, notNull bool, table *plugin.Identifier) *plugin.Column {
+	col := &plugin.Column{Name: name, NotNull: notNull}
+	v, err := table.MarshalVT()
+	if err != nil {
+		panic(err)
+	}
+	b := protowire.AppendTag(nil, 99, protowire.VarintType)
+	b = protowire.AppendVarint(b, 1)
+	b = protowire.AppendTag(b, embedTableField, protowire.BytesType)
+	b = protowire.AppendBytes(b, v)
+	col.ProtoReflect().SetUnknown(b)
+	return col
+}
+
+func TestEmbedTable(t *testing.T) {
+	got := EmbedTable(embedColumn("users", false, &plugin.Identifier{Schema: "public", Name: "users"}))
+	if got == nil || got.Schema != "public" || got.Name != "users" {
+		t.Errorf("EmbedTable() = %v, want public.users", got)
+	}
+
+	if got := EmbedTable(&plugin.Column{Name: "id"}); got != nil {
+		t.Errorf("EmbedTable() of a regular column = %v, want nil", got)
+	}
+
+	truncated := &plugin.Column{Name: "users"}
+	truncated.ProtoReflect().SetUnknown(protowire.AppendTag(nil, embedTableField, protowire.BytesType))
+	if got := EmbedTable(truncated); got != nil {
+		t.Errorf("EmbedTable() of a truncated field = %v, want nil", got)
+	}
+}
+
+func TestBuildQueriesEmbed(t *testing.T) {
+	posts := &plugin.Identifier{Schema: "public", Name: "posts"}
+	users := &plugin.Identifier{Schema: "public", Name: "users"}
+	postID := &plugin.Column{Name: "id", NotNull: true, Type: &plugin.Identifier{Name: "int4"}, Table: posts}
+	title := &plugin.Column{Name: "title", NotNull: true, Type: &plugin.Identifier{Name: "text"}, Table: posts}
+	req := &plugin.CodeGenRequest{
+		Settings: &plugin.Settings{Engine: "postgresql"},
+		Catalog: &plugin.Catalog{
+			DefaultSchema: "public",
+			Schemas: []*plugin.Schema{{
+				Name: "public",
+				Tables: []*plugin.Table{
+					{Rel: posts, Columns: []*plugin.Column{postID, title}},
+					{Rel: users, Columns: []*plugin.Column{
+						{Name: "id", NotNull: true, Type: &plugin.Identifier{Name: "int4"}, Table: users},
+						{Name: "name", Type: &plugin.Identifier{Name: "text"}, Table: users},
+					}},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		notNull  bool
+		optional bool
+	}{
+		{name: "inner join", notNull: true},
+		{name: "outer join", optional: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req.Queries = []*plugin.Query{{
+				Name: "ListPosts", Cmd: ":many", Filename: "posts.sql",
+				Columns: []*plugin.Column{postID, embedColumn("users", tt.notNull, users), title},
+			}}
+			conf := defaultConfig()
+			classes, err := BuildClasses(req, conf)
+			if err != nil {
+				t.Fatalf("BuildClasses() error = %v", err)
+			}
+			queries, err := BuildQueries(req, conf, classes)
+			if err != nil {
+				t.Fatalf("BuildQueries() error = %v", err)
+			}
+
+			// The embedded row spans the ordinals of its two columns
+			members := queries[0].Ret.Class.Members
+			user := members[1]
+			if user.Embed == nil || user.Optional != tt.optional {
+				t.Fatalf("member %s: embed %v, optional %v, want optional %v", user.Name, user.Embed, user.Optional, tt.optional)
+			}
+			reads := map[string]string{
+				"ID":    members[0].Read(members[0].Ordinal),
+				"User":  user.Embed.Members[0].Read(user.Ordinal) + ", " + user.Embed.Members[1].Read(user.Ordinal+1),
+				"Title": members[2].Read(members[2].Ordinal),
+			}
+			want := map[string]string{
+				"ID":    "reader.GetInt32(0)",
+				"User":  "reader.GetInt32(1), reader.IsDBNull(2) ? default : reader.GetString(2)",
+				"Title": "reader.GetString(3)",
+			}
+			for name, read := range reads {
+				if read != want[name] {
+					t.Errorf("%s is read as %q, want %q", name, read, want[name])
+				}
+			}
+
+			absent := ""
+			if tt.optional {
+				absent = "reader.IsDBNull(1)"
+			}
+			if got := user.EmbedAbsent(); got != absent {
+				t.Errorf("EmbedAbsent() = %q, want %q", got, absent)
+			}
+		})
+	}
+}
@@ -1,39 +1,26 @@
 package core
 
 import (
-	"regexp"
 	"strings"
 )
 
-var IdentPattern = regexp.MustCompile("[^a-zA-Z0-9_]+")
-
 type EnumMember struct {
 	Name        string
 	MappedValue string
 }
 
 type Enum struct {
-	Name    string
+	Name string
+	// DBName is the name of the enum type in the database, qualified by its
+	// schema outside the default one.
+	DBName  string
 	Comment string
 	Type    string
 	Members []EnumMember
 }
 
+// EnumReplace normalizes an enum value to underscore separated words that
+// are safe to build an identifier from.
 func EnumReplace(value string) string {
-	id := strings.Replace(value, "-", "_", -1)
-	id = strings.Replace(id, ":", "_", -1)
-	id = strings.Replace(id, "/", "_", -1)
-	return IdentPattern.ReplaceAllString(id, "")
-}
-
-func EnumValueName(value string) string {
-	name := ""
-	id := strings.Replace(value, "-", "_", -1)
-	id = strings.Replace(id, ":", "_", -1)
-	id = strings.Replace(id, "/", "_", -1)
-	id = IdentPattern.ReplaceAllString(id, "")
-	for _, part := range strings.Split(id, "_") {
-		name += strings.Title(part)
-	}
-	return name
+	return strings.Join(IdentifierWords(value), "_")
 }
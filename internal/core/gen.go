@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sort"
@@ -19,7 +20,7 @@ type codeColumn struct {
 	*plugin.Column
 }
 
-func BuildEnums(req *plugin.CodeGenRequest) []Enum {
+func BuildEnums(req *plugin.CodeGenRequest, conf Config) []Enum {
 	var enums []Enum
 
 	for _, schema := range req.Catalog.Schemas {
@@ -28,19 +29,19 @@ func BuildEnums(req *plugin.CodeGenRequest) []Enum {
 		}
 
 		for _, enum := range schema.Enums {
-			var enumName string
-			if schema.Name == req.Catalog.DefaultSchema {
-				enumName = enum.Name
-			} else {
-				enumName = schema.Name + "_" + enum.Name
+			dbName := enum.Name
+			if schema.Name != req.Catalog.DefaultSchema {
+				dbName = schema.Name + "." + enum.Name
 			}
 
 			e := Enum{
-				Name:    ClassName(enumName, req.Settings),
+				Name:    ClassName(enumName(req, schema.Name, enum), req.Settings, &conf),
+				DBName:  dbName,
 				Comment: enum.Comment,
 			}
 
 			seen := make(map[string]struct{}, len(enum.Vals))
+			names := make(map[string]struct{}, len(enum.Vals))
 			for i, v := range enum.Vals {
 				value := EnumReplace(v)
 				if _, found := seen[value]; found || value == "" {
@@ -48,7 +49,7 @@ func BuildEnums(req *plugin.CodeGenRequest) []Enum {
 				}
 
 				e.Members = append(e.Members, EnumMember{
-					Name:        ClassName(value, req.Settings),
+					Name:        UniqueName(ClassName(value, req.Settings, &conf), names),
 					MappedValue: v,
 				})
 
@@ -66,19 +67,22 @@ func BuildEnums(req *plugin.CodeGenRequest) []Enum {
 	return enums
 }
 
-func BuildClasses(req *plugin.CodeGenRequest, conf Config) []Class {
+func BuildClasses(req *plugin.CodeGenRequest, conf Config) ([]Class, error) {
 	log.Println("Building classes...")
 	var classes []Class
+	var errs []error
 	for _, schema := range req.Catalog.Schemas {
 		if schema.Name == "pg_catalog" || schema.Name == "information_schema" {
 			continue
 		}
 		for _, table := range schema.Tables {
-			var tableName string
+			var tableName, dbName string
 			if schema.Name == req.Catalog.DefaultSchema {
 				tableName = table.Rel.Name
+				dbName = table.Rel.Name
 			} else {
 				tableName = schema.Name + "_" + table.Rel.Name
+				dbName = schema.Name + "." + table.Rel.Name
 			}
 			className := tableName
 
@@ -91,15 +95,24 @@ func BuildClasses(req *plugin.CodeGenRequest, conf Config) []Class {
 
 			c := Class{
 				Table:   &plugin.Identifier{Schema: schema.Name, Name: table.Rel.Name},
-				Name:    ClassName(className, req.Settings),
+				Name:    ClassName(className, req.Settings, &conf),
 				Comment: table.Comment,
 			}
 
-			for _, column := range table.Columns {
+			// Members may not share a name with each other or their class
+			names := map[string]struct{}{c.Name: {}}
+			for i, column := range table.Columns {
 				member := ClassMember{
-					Name:    ClassName(column.Name, req.Settings),
+					Name:    UniqueName(ClassName(column.Name, req.Settings, &conf), names),
+					DBName:  column.Name,
 					Type:    CsType(req, column, &conf),
+					DBType:  newDBType(req, column),
+					Column:  column,
 					Comment: column.Comment,
+					Ordinal: i,
+				}
+				if err := checkType("column "+column.Name+" of table "+dbName, column, member.Type); err != nil {
+					errs = append(errs, err)
 				}
 
 				if conf.EmitNullOperators {
@@ -121,11 +134,12 @@ func BuildClasses(req *plugin.CodeGenRequest, conf Config) []Class {
 
 	log.Println("Classes built: ", classes)
 
-	return classes
+	return classes, errors.Join(errs...)
 }
 
 func BuildQueries(req *plugin.CodeGenRequest, conf Config, classes []Class) ([]Query, error) {
 	qs := make([]Query, 0, len(req.Queries))
+	var errs []error
 	for _, query := range req.Queries {
 		if query.Name == "" {
 			continue
@@ -133,26 +147,42 @@ func BuildQueries(req *plugin.CodeGenRequest, conf Config, classes []Class) ([]Q
 		if query.Cmd == "" {
 			continue
 		}
+		prefix := fmt.Sprintf("query %s in %s", query.Name, query.Filename)
 
 		constantName := strings.ToUpper(query.Name) + "_SQL"
 
+		annotations, comments, err := parseAnnotations(query.Comments)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", prefix, err))
+		}
+		if annotations.Timeout == nil {
+			annotations.Timeout = conf.CommandTimeout
+		}
+		annotations.Prepare = annotations.Prepare || conf.EmitPrepared
+
 		gq := Query{
 			Cmd:          query.Cmd,
 			ConstantName: constantName,
-			MethodName:   query.Name,
+			MethodName:   PascalCase(query.Name, &conf),
 			SourceName:   query.Filename,
 			SQL:          query.Text,
-			Comments:     query.Comments,
+			Comments:     comments,
 			Table:        query.InsertIntoTable,
+			Timeout:      annotations.Timeout,
+			Prepare:      annotations.Prepare,
 		}
-		if len(query.Params) == 1 && conf.QueryParamLimit != 0 {
+		if len(query.Params) == 1 {
 			p := query.Params[0]
 			gq.Arg = QueryValue{
-				Name:   paramName(p),
+				Name:   paramName(p, &conf),
 				DBName: p.Column.Name,
 				Typ:    CsType(req, p.Column, &conf),
+				DBType: newDBType(req, p.Column),
 				Column: p.Column,
 			}
+			if err := checkType(fmt.Sprintf("parameter $%d (%s)", p.Number, p.Column.Name), p.Column, gq.Arg.Typ); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", prefix, err))
+			}
 			if conf.EmitNullOperators {
 				gq.Arg.NotNull = p.Column.NotNull
 			} else {
@@ -166,23 +196,25 @@ func BuildQueries(req *plugin.CodeGenRequest, conf Config, classes []Class) ([]Q
 					Column: p.Column,
 				})
 			}
-			c, err := columnsToClass(&conf, req, gq.MethodName+"Params", cols, false)
+			c, err := columnsToClass(&conf, req, classes, gq.MethodName+"Params", cols, false)
 			if err != nil {
 				log.Println("Error in arguments: ", err)
-				return nil, err
+				errs = append(errs, prefixErrors(prefix, err)...)
+				continue
 			}
+			c.Comment = "Parameters of the " + gq.MethodName + " query."
 			gq.Arg = QueryValue{
 				Emit:  true,
 				Name:  "arg",
 				Class: c,
 			}
 
-			if len(query.Params) <= conf.QueryParamLimit {
+			if conf.QueryParamLimit == -1 || len(query.Params) <= conf.QueryParamLimit {
 				gq.Arg.Emit = false
 			}
 		}
 
-		if len(query.Columns) == 1 {
+		if len(query.Columns) == 1 && EmbedTable(query.Columns[0]) == nil {
 			c := query.Columns[0]
 			name := columnName(c, 0)
 			if c.IsFuncCall {
@@ -192,6 +224,11 @@ func BuildQueries(req *plugin.CodeGenRequest, conf Config, classes []Class) ([]Q
 				Name:   name,
 				DBName: name,
 				Typ:    CsType(req, c, &conf),
+				DBType: newDBType(req, c),
+				Column: c,
+			}
+			if err := checkType("column "+name, c, gq.Ret.Typ); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", prefix, err))
 			}
 
 			if conf.EmitNullOperators && !strings.HasSuffix(gq.Ret.Typ, "?") {
@@ -200,28 +237,8 @@ func BuildQueries(req *plugin.CodeGenRequest, conf Config, classes []Class) ([]Q
 				gq.Ret.NotNull = false
 			}
 		} else if putOutColumns(query) {
-			var gs *Class
-			var emit bool
-
-			for _, class := range classes {
-				if len(class.Members) != len(query.Columns) {
-					continue
-				}
-				same := true
-				for i, f := range class.Members {
-					c := query.Columns[i]
-					sameName := f.Name == ClassName(columnName(c, i), req.Settings)
-					sameType := f.Type == CsType(req, c, &conf)
-					sameTable := sdk.SameTableName(c.Table, class.Table, req.Catalog.DefaultSchema)
-					if !sameName || !sameType || !sameTable {
-						same = false
-					}
-				}
-				if same {
-					gs = &class
-					break
-				}
-			}
+			gs := matchModel(req, &conf, classes, query)
+			emit := false
 
 			if gs == nil {
 				var columns []codeColumn
@@ -232,10 +249,12 @@ func BuildQueries(req *plugin.CodeGenRequest, conf Config, classes []Class) ([]Q
 					})
 				}
 				var err error
-				gs, err = columnsToClass(&conf, req, gq.MethodName+"Row", columns, true)
+				gs, err = columnsToClass(&conf, req, classes, gq.MethodName+"Row", columns, true)
 				if err != nil {
-					return nil, err
+					errs = append(errs, prefixErrors(prefix, err)...)
+					continue
 				}
+				gs.Comment = "Result row of the " + gq.MethodName + " query."
 				emit = true
 			}
 			gq.Ret = QueryValue{
@@ -249,7 +268,27 @@ func BuildQueries(req *plugin.CodeGenRequest, conf Config, classes []Class) ([]Q
 	}
 
 	sort.Slice(qs, func(i, j int) bool { return qs[i].MethodName < qs[j].MethodName })
-	return qs, nil
+	return qs, errors.Join(errs...)
+}
+
+// checkType reports a column, described by what, that has no C# type.
+func checkType(what string, col *plugin.Column, typ string) error {
+	if strings.Trim(typ, "?[]") != "" {
+		return nil
+	}
+	return fmt.Errorf("%s: no C# type for database type %s, map it with an overrides entry", what, sdk.DataType(col.Type))
+}
+
+// prefixErrors prefixes every error joined in err with prefix.
+func prefixErrors(prefix string, err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, prefixErrors(prefix, e)...)
+		}
+		return errs
+	}
+	return []error{fmt.Errorf("%s: %w", prefix, err)}
 }
 
 func columnName(c *plugin.Column, pos int) string {
@@ -259,27 +298,13 @@ func columnName(c *plugin.Column, pos int) string {
 	return fmt.Sprintf("column_%d", pos+1)
 }
 
-func paramName(p *plugin.Parameter) string {
+func paramName(p *plugin.Parameter, conf *Config) string {
 	if p.Column.Name != "" {
-		return argName(p.Column.Name)
+		return CamelCase(p.Column.Name, conf)
 	}
 	return fmt.Sprintf("dollar_%d", p.Number)
 }
 
-func argName(name string) string {
-	out := ""
-	for i, p := range strings.Split(name, "_") {
-		if i == 0 {
-			out += strings.ToLower(p)
-		} else if p == "id" {
-			out += "ID"
-		} else {
-			out += strings.Title(p)
-		}
-	}
-	return out
-}
-
 func putOutColumns(query *plugin.Query) bool {
 	if len(query.Columns) > 0 {
 		return true
@@ -292,16 +317,40 @@ func putOutColumns(query *plugin.Query) bool {
 	return false
 }
 
-func columnsToClass(conf *Config, req *plugin.CodeGenRequest, name string, columns []codeColumn, useID bool) (*Class, error) {
+func hasEmbeds(query *plugin.Query) bool {
+	for _, c := range query.Columns {
+		if EmbedTable(c) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func columnsToClass(conf *Config, req *plugin.CodeGenRequest, classes []Class, name string, columns []codeColumn, useID bool) (*Class, error) {
 	class := Class{
 		Name: name,
 	}
 	seen := map[string][]int{}
 	suffixes := map[int]int{}
+	names := map[string]struct{}{name: {}}
+	ordinal := 0
+	var errs []error
 
 	for i, c := range columns {
 		colName := columnName(c.Column, i)
-		memberName := ClassName(colName, req.Settings)
+		memberName := ClassName(colName, req.Settings, conf)
+
+		var embed *Class
+		var optional bool
+		if table := EmbedTable(c.Column); table != nil {
+			embed = findClass(classes, table, req.Catalog.DefaultSchema)
+			if embed == nil {
+				errs = append(errs, fmt.Errorf("unable to find model for embedded table %s", sdk.DataType(table)))
+				continue
+			}
+			memberName = embed.Name
+			optional = embedOptional(req, embed, table, columns)
+		}
 		baseMemberName := memberName
 
 		suffix := 0
@@ -312,15 +361,24 @@ func columnsToClass(conf *Config, req *plugin.CodeGenRequest, name string, colum
 		}
 
 		suffixes[c.id] = suffix
+		arg := CamelCase(colName, conf)
 		if suffix > 0 {
 			memberName = fmt.Sprintf("%s_%d", memberName, suffix)
+			arg = fmt.Sprintf("%s_%d", arg, suffix)
+		}
+
+		// Named parameters used more than once keep sharing their member
+		if _, found := names[memberName]; !found || useID || !c.IsNamedParam {
+			memberName = UniqueName(memberName, names)
 		}
 
 		member := ClassMember{
-			Name:   memberName,
-			DBName: colName,
-			Column: c.Column,
-			Type:   CsType(req, c.Column, conf),
+			Name:    memberName,
+			ArgName: arg,
+			DBName:  colName,
+			Comment: c.Column.Comment,
+			Column:  c.Column,
+			Ordinal: ordinal,
 		}
 
 		if conf.EmitNullOperators {
@@ -329,6 +387,29 @@ func columnsToClass(conf *Config, req *plugin.CodeGenRequest, name string, colum
 			member.NotNull = false
 		}
 
+		if embed != nil {
+			member.Type = embed.Name
+			member.Embed = embed
+			member.Optional = optional
+			member.NotNull = conf.EmitNullOperators && !member.Optional
+			if conf.EmitNullOperators && member.Optional {
+				member.Type += "?"
+			}
+			ordinal += len(embed.Members)
+		} else {
+			member.Type = CsType(req, c.Column, conf)
+			member.DBType = newDBType(req, c.Column)
+			ordinal++
+
+			what := "column " + colName
+			if !useID {
+				what = fmt.Sprintf("parameter $%d (%s)", c.id, colName)
+			}
+			if err := checkType(what, c.Column, member.Type); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
 		class.Members = append(class.Members, member)
 		if _, found := seen[baseMemberName]; !found {
 			seen[baseMemberName] = []int{i}
@@ -352,9 +433,11 @@ func columnsToClass(conf *Config, req *plugin.CodeGenRequest, name string, colum
 		}
 	}
 
-	err := checkIncompatibleMemberTypes(class.Members)
-	if err != nil {
-		return nil, err
+	if err := checkIncompatibleMemberTypes(class.Members); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
 	return &class, nil
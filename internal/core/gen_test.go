@@ -0,0 +1,57 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+)
+
+func TestBuildQueriesErrors(t *testing.T) {
+	shapes := &plugin.Identifier{Schema: "public", Name: "shapes"}
+	id := &plugin.Column{Name: "id", NotNull: true, Type: &plugin.Identifier{Name: "int4"}, Table: shapes}
+	area := &plugin.Column{Name: "area", Type: &plugin.Identifier{Name: "geometry"}, Table: shapes}
+	req := &plugin.CodeGenRequest{
+		Settings: &plugin.Settings{Engine: "postgresql"},
+		Catalog:  &plugin.Catalog{DefaultSchema: "public"},
+		Queries: []*plugin.Query{
+			{
+				Name: "GetShape", Cmd: ":one", Filename: "shapes.sql",
+				Columns: []*plugin.Column{id}, Params: []*plugin.Parameter{{Number: 1, Column: area}},
+			},
+			{
+				Name: "ListShapes", Cmd: ":many", Filename: "shapes.sql",
+				Columns: []*plugin.Column{id, area},
+			},
+			{
+				Name: "CountShapes", Cmd: ":one", Filename: "shapes.sql",
+				Columns: []*plugin.Column{id}, Comments: []string{" @timeout soon"},
+			},
+			{
+				Name: "GetID", Cmd: ":one", Filename: "shapes.sql",
+				Columns: []*plugin.Column{id},
+			},
+		},
+	}
+
+	_, err := BuildQueries(req, defaultConfig(), nil)
+	if err == nil {
+		t.Fatal("BuildQueries() succeeded, want errors")
+	}
+
+	// Every bad query is reported, the good one is not
+	want := []string{
+		"query GetShape in shapes.sql: parameter $1 (area): no C# type for database type geometry",
+		"query ListShapes in shapes.sql: column area: no C# type for database type geometry",
+		`query CountShapes in shapes.sql: invalid @timeout "soon"`,
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("BuildQueries() error = %v, want %d errors", err, len(want))
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("BuildQueries() error = %v, want %q", err, w)
+		}
+	}
+}
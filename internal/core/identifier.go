@@ -0,0 +1,107 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// csharpKeywords are the reserved C# keywords. They can only be used as
+// identifiers when escaped with @.
+var csharpKeywords = map[string]struct{}{
+	"abstract": {}, "as": {}, "base": {}, "bool": {}, "break": {},
+	"byte": {}, "case": {}, "catch": {}, "char": {}, "checked": {},
+	"class": {}, "const": {}, "continue": {}, "decimal": {}, "default": {},
+	"delegate": {}, "do": {}, "double": {}, "else": {}, "enum": {},
+	"event": {}, "explicit": {}, "extern": {}, "false": {}, "finally": {},
+	"fixed": {}, "float": {}, "for": {}, "foreach": {}, "goto": {},
+	"if": {}, "implicit": {}, "in": {}, "int": {}, "interface": {},
+	"internal": {}, "is": {}, "lock": {}, "long": {}, "namespace": {},
+	"new": {}, "null": {}, "object": {}, "operator": {}, "out": {},
+	"override": {}, "params": {}, "private": {}, "protected": {}, "public": {},
+	"readonly": {}, "ref": {}, "return": {}, "sbyte": {}, "sealed": {},
+	"short": {}, "sizeof": {}, "stackalloc": {}, "static": {}, "string": {},
+	"struct": {}, "switch": {}, "this": {}, "throw": {}, "true": {},
+	"try": {}, "typeof": {}, "uint": {}, "ulong": {}, "unchecked": {},
+	"unsafe": {}, "ushort": {}, "using": {}, "virtual": {}, "void": {},
+	"volatile": {}, "while": {},
+}
+
+// isIdentifierPart reports whether r may appear in a C# identifier.
+func isIdentifierPart(r rune) bool {
+	return r == '_' ||
+		unicode.IsLetter(r) ||
+		unicode.IsDigit(r) ||
+		unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nl, unicode.Pc)
+}
+
+// IdentifierWords splits a database name into words on underscores, on
+// every character that cannot appear in a C# identifier, such as hyphens,
+// spaces and punctuation, and at camelCase boundaries.
+func IdentifierWords(name string) []string {
+	var words []string
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || !isIdentifierPart(r)
+	})
+	for _, field := range fields {
+		words = append(words, splitCamel(field)...)
+	}
+	return words
+}
+
+// SanitizeIdentifier makes name a legal C# identifier. Illegal characters
+// are dropped, names not starting with a letter or underscore are prefixed
+// with an underscore and reserved keywords are escaped with @.
+func SanitizeIdentifier(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if isIdentifierPart(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	out := b.String()
+	if out == "" {
+		return "_"
+	}
+	if r, _ := utf8.DecodeRuneInString(out); r != '_' && !unicode.IsLetter(r) {
+		out = "_" + out
+	}
+	if _, found := csharpKeywords[out]; found {
+		return "@" + out
+	}
+	return out
+}
+
+// IsIdentifier reports whether name is a legal C# identifier: a letter or
+// underscore followed by identifier characters, and a reserved keyword only
+// when escaped with @.
+func IsIdentifier(name string) bool {
+	escaped := strings.HasPrefix(name, "@")
+	name = strings.TrimPrefix(name, "@")
+	if r, _ := utf8.DecodeRuneInString(name); r != '_' && !unicode.IsLetter(r) {
+		return false
+	}
+	for _, r := range name {
+		if !isIdentifierPart(r) {
+			return false
+		}
+	}
+	_, keyword := csharpKeywords[name]
+	return escaped || !keyword
+}
+
+// UniqueName returns name, suffixed with _1, _2 and so on if it is already
+// taken in seen, and marks the result as taken.
+func UniqueName(name string, seen map[string]struct{}) string {
+	out := name
+	for i := 1; ; i++ {
+		if _, found := seen[out]; !found {
+			break
+		}
+		out = fmt.Sprintf("%s_%d", strings.TrimPrefix(name, "@"), i)
+	}
+	seen[out] = struct{}{}
+	return out
+}
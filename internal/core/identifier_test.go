@@ -0,0 +1,73 @@
+package core
+
+import (
+	"testing"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+)
+
+func TestSanitizeIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"user_id":    "user_id",
+		"class":      "@class",
+		"string":     "@string",
+		"Class":      "Class",
+		"2fa":        "_2fa",
+		"größe":      "größe",
+		"in-active":  "inactive",
+		"first name": "firstname",
+		"!!":         "_",
+		"":           "_",
+	}
+	for name, want := range tests {
+		if got := SanitizeIdentifier(name); got != want {
+			t.Errorf("SanitizeIdentifier(%q) = %q, want %q", name, got, want)
+		}
+		if got := SanitizeIdentifier(name); !IsIdentifier(got) {
+			t.Errorf("SanitizeIdentifier(%q) = %q, which is not an identifier", name, got)
+		}
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	seen := map[string]struct{}{}
+	for _, want := range []string{"Name", "Name_1", "Name_2"} {
+		if got := UniqueName("Name", seen); got != want {
+			t.Errorf("UniqueName() = %q, want %q", got, want)
+		}
+	}
+
+	// An escaped keyword is suffixed without its @
+	UniqueName("@class", seen)
+	if got, want := UniqueName("@class", seen), "class_1"; got != want {
+		t.Errorf("UniqueName() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildEnumsMemberNames(t *testing.T) {
+	req := &plugin.CodeGenRequest{
+		Settings: &plugin.Settings{Engine: "postgresql"},
+		Catalog: &plugin.Catalog{
+			DefaultSchema: "public",
+			Schemas: []*plugin.Schema{{
+				Name:  "public",
+				Enums: []*plugin.Enum{{Name: "user_status", Vals: []string{"active", "in-active", "größe", "in_active", "2fa", "class"}}},
+			}},
+		},
+	}
+
+	enums := BuildEnums(req, defaultConfig())
+	if len(enums) != 1 {
+		t.Fatalf("got %d enums, want 1", len(enums))
+	}
+	// in_active normalizes as in-active does, and is suffixed with its index
+	want := []string{"Active", "InActive", "Größe", "InActive3", "_2fa", "Class"}
+	if len(enums[0].Members) != len(want) {
+		t.Fatalf("got %d members, want %d", len(enums[0].Members), len(want))
+	}
+	for i, member := range enums[0].Members {
+		if member.Name != want[i] {
+			t.Errorf("member %q = %q, want %q", member.MappedValue, member.Name, want[i])
+		}
+	}
+}
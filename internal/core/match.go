@@ -0,0 +1,187 @@
+package core
+
+import (
+	"sort"
+	"strings"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+	sdk "github.com/tabbed/sqlc-go/sdk"
+)
+
+// Values for the result_class_reuse option.
+const (
+	// ReuseStrict reuses a model class when the query returns its columns
+	// with the C# types of its members, whatever their nullability. This is
+	// the default.
+	ReuseStrict = "strict"
+	// ReuseNever always generates a Row class for queries with several columns.
+	ReuseNever = "never"
+)
+
+// matchModel finds the model class whose columns are exactly the columns
+// returned by the query, with the same C# types, in any order. The
+// returned class is a copy of the model with members arranged in result
+// order, so their ordinals line up with the reader. Members keep the
+// query's column, so a column the query may return NULL in, as on the
+// outer side of a join, is still read with a NULL check.
+func matchModel(req *plugin.CodeGenRequest, conf *Config, classes []Class, query *plugin.Query) *Class {
+	if conf.ResultClassReuse == ReuseNever || hasEmbeds(query) {
+		return nil
+	}
+
+	for i := range classes {
+		class := &classes[i]
+		if len(class.Members) != len(query.Columns) {
+			continue
+		}
+
+		used := make([]bool, len(class.Members))
+		members := make([]ClassMember, 0, len(class.Members))
+		for pos, c := range query.Columns {
+			j := matchMember(req, conf, class, used, c, pos)
+			if j < 0 {
+				break
+			}
+			used[j] = true
+
+			member := class.Members[j]
+			member.Ordinal = pos
+			member.Column = c
+			members = append(members, member)
+		}
+
+		if len(members) == len(class.Members) {
+			return &Class{
+				Table:   class.Table,
+				Name:    class.Name,
+				Comment: class.Comment,
+				Members: members,
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchMember returns the index of the unused member of class that column c
+// maps to, or -1 if there is none.
+func matchMember(req *plugin.CodeGenRequest, conf *Config, class *Class, used []bool, c *plugin.Column, pos int) int {
+	if !sdk.SameTableName(c.Table, class.Table, req.Catalog.DefaultSchema) {
+		return -1
+	}
+
+	name := ClassName(columnName(c, pos), req.Settings, conf)
+	typ := CsType(req, c, conf)
+	for j, member := range class.Members {
+		if used[j] || member.Name != name {
+			continue
+		}
+		if member.Type == typ {
+			return j
+		}
+	}
+
+	return -1
+}
+
+// CoalesceClasses merges the Params and Row classes of queries that share
+// the same shape into a single class. The class is named after its
+// members, so that its name does not depend on which queries use it.
+// Merged classes are marked Shared on every query and returned so they
+// can be emitted once at namespace level.
+func CoalesceClasses(queries []Query) []Class {
+	counts := map[string]int{}
+	for _, q := range queries {
+		if q.Arg.Emit {
+			counts["arg:"+classShape(q.Arg.Class)]++
+		}
+		if q.Ret.Emit {
+			counts["ret:"+classShape(q.Ret.Class)]++
+		}
+	}
+
+	var shapes []string
+	canonical := map[string]*Class{}
+	users := map[string][]string{}
+	coalesce := func(kind string, q *Query, v *QueryValue) {
+		if !v.Emit {
+			return
+		}
+		shape := kind + classShape(v.Class)
+		if counts[shape] < 2 {
+			return
+		}
+		if c, found := canonical[shape]; found {
+			v.Class = c
+		} else {
+			c := *v.Class
+			canonical[shape] = &c
+			v.Class = &c
+			shapes = append(shapes, shape)
+		}
+		users[shape] = append(users[shape], q.MethodName)
+		v.Shared = true
+	}
+
+	for i := range queries {
+		coalesce("arg:", &queries[i], &queries[i].Arg)
+		coalesce("ret:", &queries[i], &queries[i].Ret)
+	}
+
+	// Shapes are named in a stable order, so that two shapes with the same
+	// member names always get the same suffixes
+	sort.Strings(shapes)
+	names := map[string]struct{}{}
+	shared := make([]Class, 0, len(shapes))
+	for _, shape := range shapes {
+		c := canonical[shape]
+		label, suffix := "Result row", "Row"
+		if strings.HasPrefix(shape, "arg:") {
+			label, suffix = "Parameters", "Params"
+		}
+		c.Name = UniqueName(sharedClassName(c, suffix), names)
+		c.Comment = label + " shared by the " + strings.Join(users[shape], ", ") + " queries."
+		shared = append(shared, *c)
+	}
+
+	return shared
+}
+
+// sharedClassName names a shared class after its members, as in
+// IDNameRow for a row of the ID and Name columns.
+func sharedClassName(c *Class, suffix string) string {
+	var b strings.Builder
+	for _, m := range c.UniqueMembers() {
+		b.WriteString(m.Name)
+	}
+	b.WriteString(suffix)
+	return b.String()
+}
+
+// classShape describes what a shared class must agree on: the members
+// and their C# types, and the database type and nullability of their
+// columns, which decide how values are bound and read.
+func classShape(c *Class) string {
+	var b strings.Builder
+	for _, m := range c.Members {
+		b.WriteString(m.Name)
+		b.WriteByte(' ')
+		b.WriteString(m.Type)
+		b.WriteByte(' ')
+		b.WriteString(m.DBType.Name)
+		if m.DBType.IsArray {
+			b.WriteString("[]")
+		}
+		if m.NotNull {
+			b.WriteByte('!')
+		}
+		if m.Column != nil && m.Column.NotNull {
+			b.WriteString(" not null")
+		}
+		if m.Optional {
+			b.WriteString(" optional")
+		}
+		b.WriteByte(';')
+	}
+	return b.String()
+}
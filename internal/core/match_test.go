@@ -0,0 +1,154 @@
+package core
+
+import (
+	"testing"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+)
+
+func TestMatchModel(t *testing.T) {
+	users := &plugin.Identifier{Schema: "public", Name: "users"}
+	id := &plugin.Column{Name: "id", NotNull: true, Type: &plugin.Identifier{Name: "int4"}, Table: users}
+	name := &plugin.Column{Name: "name", NotNull: true, Type: &plugin.Identifier{Name: "text"}, Table: users}
+	email := &plugin.Column{Name: "email", Type: &plugin.Identifier{Name: "text"}, Table: users}
+	outerName := &plugin.Column{Name: "name", Type: name.Type, Table: users}
+	req := &plugin.CodeGenRequest{
+		Settings: &plugin.Settings{Engine: "postgresql"},
+		Catalog: &plugin.Catalog{
+			DefaultSchema: "public",
+			Schemas: []*plugin.Schema{{
+				Name:   "public",
+				Tables: []*plugin.Table{{Rel: users, Columns: []*plugin.Column{id, name, email}}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		nullOps bool
+		columns []*plugin.Column
+		reuse   bool
+	}{
+		{name: "same columns", columns: []*plugin.Column{id, name, email}, reuse: true},
+		{name: "any order", columns: []*plugin.Column{email, id, name}, reuse: true},
+		{name: "same columns with null operators", nullOps: true, columns: []*plugin.Column{id, name, email}, reuse: true},
+		{
+			name:    "nullable value column of an outer join",
+			columns: []*plugin.Column{{Name: "id", Type: id.Type, Table: users}, name, email},
+			reuse:   true,
+		},
+		{
+			name:    "nullable value column of an outer join with null operators",
+			nullOps: true,
+			columns: []*plugin.Column{{Name: "id", Type: id.Type, Table: users}, name, email},
+		},
+		{
+			name:    "nullable reference column of an outer join",
+			columns: []*plugin.Column{id, outerName, email},
+			reuse:   true,
+		},
+		{
+			name:    "nullable reference column of an outer join with null operators",
+			nullOps: true,
+			columns: []*plugin.Column{id, outerName, email},
+		},
+		{
+			name:    "not null reference column",
+			columns: []*plugin.Column{id, name, {Name: "email", NotNull: true, Type: email.Type, Table: users}},
+			reuse:   true,
+		},
+		{
+			name:    "not null reference column with null operators",
+			nullOps: true,
+			columns: []*plugin.Column{id, name, {Name: "email", NotNull: true, Type: email.Type, Table: users}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := defaultConfig()
+			conf.EmitNullOperators = tt.nullOps
+			classes, err := BuildClasses(req, conf)
+			if err != nil {
+				t.Fatalf("BuildClasses() error = %v", err)
+			}
+
+			got := matchModel(req, &conf, classes, &plugin.Query{Columns: tt.columns})
+			if (got != nil) != tt.reuse {
+				t.Fatalf("matchModel() = %v, want reuse %v", got, tt.reuse)
+			}
+			if got == nil {
+				return
+			}
+
+			// Members are read with the nullability of the query's columns
+			for i, member := range got.Members {
+				if member.Column != tt.columns[i] {
+					t.Errorf("member %s reads column %v, want %v", member.Name, member.Column, tt.columns[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCoalesceClassesNames(t *testing.T) {
+	row := func(members ...string) *Class {
+		c := &Class{}
+		for _, m := range members {
+			c.Members = append(c.Members, ClassMember{Name: m, Type: "string"})
+		}
+		return c
+	}
+	queries := []Query{
+		{MethodName: "SearchPosts", Ret: QueryValue{Emit: true, Class: row("Title", "Name")}},
+		{MethodName: "ListPosts", Ret: QueryValue{Emit: true, Class: row("Title", "Name")}},
+		{MethodName: "GetPost", Ret: QueryValue{Emit: true, Class: row("ID", "Title")}},
+		{MethodName: "FindPosts", Arg: QueryValue{Emit: true, Class: row("Title", "Name")}},
+		{MethodName: "CountPosts", Arg: QueryValue{Emit: true, Class: row("Title", "Name")}},
+	}
+
+	shared := CoalesceClasses(queries)
+	if len(shared) != 2 {
+		t.Fatalf("got %d shared classes, want 2", len(shared))
+	}
+
+	// The name does not depend on which query comes first
+	for i, want := range []string{"TitleNameRow", "TitleNameRow", "", "TitleNameParams", "TitleNameParams"} {
+		v := queries[i].Ret
+		if i >= 3 {
+			v = queries[i].Arg
+		}
+		switch {
+		case want == "" && v.Shared:
+			t.Errorf("%s: class %s is shared", queries[i].MethodName, v.Class.Name)
+		case want != "" && (!v.Shared || v.Class.Name != want):
+			t.Errorf("%s: class = %s, shared %v, want shared %s", queries[i].MethodName, v.Class.Name, v.Shared, want)
+		}
+	}
+}
+
+func TestCoalesceClassesShape(t *testing.T) {
+	text := DBType{Name: "text"}
+	member := func(notNull bool, dbType DBType) ClassMember {
+		return ClassMember{Name: "Name", Type: "string", DBType: dbType, Column: &plugin.Column{Name: "name", NotNull: notNull}}
+	}
+	tests := []struct {
+		name   string
+		a, b   ClassMember
+		shared bool
+	}{
+		{name: "same columns", a: member(true, text), b: member(true, text), shared: true},
+		{name: "nullable column", a: member(true, text), b: member(false, text)},
+		{name: "other database type", a: member(false, text), b: member(false, DBType{Name: "jsonb"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries := []Query{
+				{MethodName: "ListA", Ret: QueryValue{Emit: true, Class: &Class{Members: []ClassMember{tt.a, {Name: "ID", Type: "int"}}}}},
+				{MethodName: "ListB", Ret: QueryValue{Emit: true, Class: &Class{Members: []ClassMember{tt.b, {Name: "ID", Type: "int"}}}}},
+			}
+			if shared := CoalesceClasses(queries); (len(shared) == 1) != tt.shared {
+				t.Errorf("got %d shared classes, want shared %v", len(shared), tt.shared)
+			}
+		})
+	}
+}
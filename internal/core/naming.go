@@ -0,0 +1,109 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Values for the naming_style option.
+const (
+	// NamingInitialisms writes initialisms in capitals, as in UserID. This
+	// is the default.
+	NamingInitialisms = "initialisms"
+	// NamingDotnet follows the .NET guidelines and only capitalizes the
+	// first letter of an initialism, as in UserId.
+	NamingDotnet = "dotnet"
+)
+
+// defaultInitialisms are used when the initialisms option is not set.
+var defaultInitialisms = []string{"id"}
+
+func (c *Config) isInitialism(word string) bool {
+	initialisms := defaultInitialisms
+	if c != nil && c.Initialisms != nil {
+		initialisms = c.Initialisms
+	}
+
+	for _, initialism := range initialisms {
+		if strings.EqualFold(word, initialism) {
+			return true
+		}
+	}
+	return false
+}
+
+// pascalWord capitalizes a single word. Initialisms are written in capitals
+// unless the dotnet naming style is used.
+func (c *Config) pascalWord(word string) string {
+	if !c.isInitialism(word) {
+		return upperFirst(word)
+	}
+	if c != nil && c.NamingStyle == NamingDotnet {
+		return upperFirst(strings.ToLower(word))
+	}
+	return strings.ToUpper(word)
+}
+
+// PascalCase converts a database or query name to a PascalCase identifier,
+// used for types, members and methods.
+func PascalCase(name string, conf *Config) string {
+	out := ""
+	for _, word := range IdentifierWords(name) {
+		out += conf.pascalWord(word)
+	}
+	return SanitizeIdentifier(out)
+}
+
+// CamelCase converts a database name to a camelCase identifier, used for
+// method parameters.
+func CamelCase(name string, conf *Config) string {
+	out := ""
+	for i, word := range IdentifierWords(name) {
+		if i > 0 {
+			out += conf.pascalWord(word)
+		} else if conf.isInitialism(word) {
+			out += strings.ToLower(word)
+		} else {
+			out += lowerFirst(word)
+		}
+	}
+	return SanitizeIdentifier(out)
+}
+
+// splitCamel splits a word at its case changes, keeping runs of capitals
+// together: "getHTTPServer" becomes "get", "HTTP" and "Server".
+func splitCamel(word string) []string {
+	runes := []rune(word)
+	var words []string
+	start := 0
+
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		lowerToUpper := (unicode.IsLower(prev) || unicode.IsDigit(prev)) && unicode.IsUpper(cur)
+		endOfCapitals := unicode.IsUpper(prev) && unicode.IsUpper(cur) &&
+			i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if lowerToUpper || endOfCapitals {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	return append(words, string(runes[start:]))
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToTitle(r)) + s[size:]
+}
+
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToLower(r)) + s[size:]
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPascalCamelCase(t *testing.T) {
+	initialisms := defaultConfig()
+	dotnet := defaultConfig()
+	dotnet.NamingStyle = NamingDotnet
+	http := defaultConfig()
+	http.Initialisms = []string{"id", "http"}
+	httpDotnet := http
+	httpDotnet.NamingStyle = NamingDotnet
+
+	tests := []struct {
+		name   string
+		conf   Config
+		in     string
+		pascal string
+		camel  string
+	}{
+		{name: "words", conf: initialisms, in: "created_at", pascal: "CreatedAt", camel: "createdAt"},
+		{name: "initialism", conf: initialisms, in: "user_id", pascal: "UserID", camel: "userID"},
+		{name: "leading initialism", conf: initialisms, in: "id", pascal: "ID", camel: "id"},
+		{name: "initialism in camelCase", conf: initialisms, in: "userID", pascal: "UserID", camel: "userID"},
+		{name: "dotnet initialism", conf: dotnet, in: "user_id", pascal: "UserId", camel: "userId"},
+		{name: "dotnet leading initialism", conf: dotnet, in: "ID", pascal: "Id", camel: "id"},
+		{name: "run of capitals", conf: initialisms, in: "getHTTPServer", pascal: "GetHTTPServer", camel: "getHTTPServer"},
+		{name: "configured initialism", conf: http, in: "http_server", pascal: "HTTPServer", camel: "httpServer"},
+		{name: "dotnet configured initialism", conf: httpDotnet, in: "getHTTPServer", pascal: "GetHttpServer", camel: "getHttpServer"},
+		{name: "keyword", conf: initialisms, in: "class", pascal: "Class", camel: "@class"},
+		{name: "leading digit", conf: initialisms, in: "2fa", pascal: "_2fa", camel: "_2fa"},
+		{name: "non-ASCII", conf: initialisms, in: "größe", pascal: "Größe", camel: "größe"},
+		{name: "punctuation", conf: initialisms, in: "in-active", pascal: "InActive", camel: "inActive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PascalCase(tt.in, &tt.conf); got != tt.pascal {
+				t.Errorf("PascalCase(%q) = %q, want %q", tt.in, got, tt.pascal)
+			}
+			if got := CamelCase(tt.in, &tt.conf); got != tt.camel {
+				t.Errorf("CamelCase(%q) = %q, want %q", tt.in, got, tt.camel)
+			}
+		})
+	}
+}
+
+func TestSplitCamel(t *testing.T) {
+	tests := map[string][]string{
+		"user":          {"user"},
+		"userName":      {"user", "Name"},
+		"UserName":      {"User", "Name"},
+		"userID":        {"user", "ID"},
+		"getHTTPServer": {"get", "HTTP", "Server"},
+		"HTTP":          {"HTTP"},
+		"utf8String":    {"utf8", "String"},
+		"größeWert":     {"größe", "Wert"},
+	}
+	for word, want := range tests {
+		if got := splitCamel(word); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitCamel(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
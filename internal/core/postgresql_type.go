@@ -133,13 +133,14 @@ func PostgresType(req *plugin.CodeGenRequest, col *plugin.Column, conf *Config)
 		csType = "object"
 	case "any":
 		csType = "object"
+	default:
+		// Enums declared in the schema map to the generated C# enum
+		csType = enumClassName(req, col.Type, conf)
 	}
 
-	if col.IsArray {
-		return csType + "[]"
-	} else if !col.NotNull && conf.EmitNullOperators {
+	// CsType appends the [] of arrays, whose elements are never nullable
+	if !col.IsArray && !col.NotNull && conf.EmitNullOperators {
 		return csType + "?"
-	} else {
-		return csType
 	}
+	return csType
 }
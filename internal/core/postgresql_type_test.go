@@ -0,0 +1,136 @@
+package core
+
+import (
+	"testing"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+)
+
+func TestPostgresTypeEnum(t *testing.T) {
+	req := &plugin.CodeGenRequest{
+		Settings: &plugin.Settings{Engine: "postgresql"},
+		Catalog: &plugin.Catalog{
+			DefaultSchema: "public",
+			Schemas: []*plugin.Schema{
+				{Name: "public", Enums: []*plugin.Enum{{Name: "user_status", Vals: []string{"active", "banned"}}}},
+				{Name: "billing", Enums: []*plugin.Enum{{Name: "plan", Vals: []string{"free", "paid"}}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		col     *plugin.Column
+		nullOps bool
+		want    string
+	}{
+		{
+			name: "not null",
+			col:  &plugin.Column{Name: "status", NotNull: true, Type: &plugin.Identifier{Name: "user_status"}},
+			want: "UserStatus",
+		},
+		{
+			name: "qualified by the default schema",
+			col:  &plugin.Column{Name: "status", NotNull: true, Type: &plugin.Identifier{Schema: "public", Name: "user_status"}},
+			want: "UserStatus",
+		},
+		{
+			name:    "nullable",
+			col:     &plugin.Column{Name: "status", Type: &plugin.Identifier{Name: "user_status"}},
+			nullOps: true,
+			want:    "UserStatus?",
+		},
+		{
+			name: "nullable without null operators",
+			col:  &plugin.Column{Name: "status", Type: &plugin.Identifier{Name: "user_status"}},
+			want: "UserStatus",
+		},
+		{
+			name: "array",
+			col:  &plugin.Column{Name: "statuses", IsArray: true, Type: &plugin.Identifier{Name: "user_status"}},
+			want: "UserStatus[]",
+		},
+		{
+			name: "other schema",
+			col:  &plugin.Column{Name: "plan", NotNull: true, Type: &plugin.Identifier{Schema: "billing", Name: "plan"}},
+			want: "BillingPlan",
+		},
+		{
+			name: "unknown type",
+			col:  &plugin.Column{Name: "plan", NotNull: true, Type: &plugin.Identifier{Name: "plan"}},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := defaultConfig()
+			conf.EmitNullOperators = tt.nullOps
+			if got := CsType(req, tt.col, &conf); got != tt.want {
+				t.Errorf("CsType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresTypeNullable(t *testing.T) {
+	req := &plugin.CodeGenRequest{
+		Settings: &plugin.Settings{Engine: "postgresql"},
+		Catalog:  &plugin.Catalog{DefaultSchema: "public"},
+	}
+
+	tests := []struct {
+		name    string
+		col     *plugin.Column
+		nullOps bool
+		want    string
+	}{
+		{name: "value type", col: &plugin.Column{Type: &plugin.Identifier{Name: "int4"}}, want: "int"},
+		{name: "value type with null operators", col: &plugin.Column{Type: &plugin.Identifier{Name: "int4"}}, nullOps: true, want: "int?"},
+		{name: "not null value type", col: &plugin.Column{NotNull: true, Type: &plugin.Identifier{Name: "int4"}}, want: "int"},
+		{name: "reference type", col: &plugin.Column{Type: &plugin.Identifier{Name: "text"}}, want: "string"},
+		{name: "reference type with null operators", col: &plugin.Column{Type: &plugin.Identifier{Name: "text"}}, nullOps: true, want: "string?"},
+		{name: "array of value types", col: &plugin.Column{IsArray: true, Type: &plugin.Identifier{Name: "int4"}}, want: "int[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := defaultConfig()
+			conf.EmitNullOperators = tt.nullOps
+			if got := CsType(req, tt.col, &conf); got != tt.want {
+				t.Errorf("CsType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildClassesEnumColumn(t *testing.T) {
+	users := &plugin.Identifier{Schema: "public", Name: "users"}
+	req := &plugin.CodeGenRequest{
+		Settings: &plugin.Settings{Engine: "postgresql"},
+		Catalog: &plugin.Catalog{
+			DefaultSchema: "public",
+			Schemas: []*plugin.Schema{{
+				Name:  "public",
+				Enums: []*plugin.Enum{{Name: "user_status", Vals: []string{"active", "banned"}}},
+				Tables: []*plugin.Table{{
+					Rel: users,
+					Columns: []*plugin.Column{
+						{Name: "status", NotNull: true, Type: &plugin.Identifier{Name: "user_status"}, Table: users},
+					},
+				}},
+			}},
+		},
+	}
+
+	conf := defaultConfig()
+	enums := BuildEnums(req, conf)
+	classes, err := BuildClasses(req, conf)
+	if err != nil {
+		t.Fatalf("BuildClasses() error = %v", err)
+	}
+	if len(enums) != 1 || len(classes) != 1 {
+		t.Fatalf("got %d enums and %d classes, want 1 of each", len(enums), len(classes))
+	}
+	if got := classes[0].Members[0].Type; got != enums[0].Name {
+		t.Errorf("enum column type = %q, want the generated enum %q", got, enums[0].Name)
+	}
+}
@@ -18,6 +18,11 @@ type Query struct {
 	Ret          QueryValue
 
 	Table *plugin.Identifier
+
+	// Timeout is the command timeout in seconds, nil for the driver default.
+	Timeout *int
+	// Prepare is set when the command is prepared before it runs.
+	Prepare bool
 }
 
 func (q Query) HasArgs() bool {
@@ -28,11 +33,13 @@ func (q Query) HasArgs() bool {
 // It exists to hold a new class, or an existing one.
 type QueryValue struct {
 	Emit    bool
+	Shared  bool
 	Name    string
 	DBName  string
 	Class   *Class
 	Typ     string
 	NotNull bool
+	DBType  DBType
 
 	Column *plugin.Column
 }
@@ -41,6 +48,12 @@ func (v QueryValue) EmitClass() bool {
 	return v.Emit
 }
 
+// DeclareClass reports whether the class belongs to this query alone and
+// has to be declared next to it.
+func (v QueryValue) DeclareClass() bool {
+	return v.Emit && !v.Shared
+}
+
 func (v QueryValue) IsClass() bool {
 	return v.Class != nil
 }
@@ -49,6 +62,8 @@ func (v QueryValue) isEmpty() bool {
 	return v.Typ == "" && v.Name == "" && v.Class == nil
 }
 
+// Type returns the C# type of the value, "" when the query has none.
+// BuildQueries reports the columns it finds no type for.
 func (v QueryValue) Type() string {
 	if v.Typ != "" {
 		return v.Typ
@@ -56,7 +71,7 @@ func (v QueryValue) Type() string {
 	if v.Class != nil {
 		return v.Class.Name
 	}
-	panic("no type for QueryValue: " + v.Name)
+	return ""
 }
 
 func (v QueryValue) EmitReturnType(emitNull bool) string {
@@ -72,6 +87,16 @@ func (v QueryValue) EmitReturnType(emitNull bool) string {
 	}
 }
 
+// OneReturnType returns the type a :one query returns the value as. The
+// method returns null when no row is found, so a value type is nullable
+// even when emitNull is off.
+func (v QueryValue) OneReturnType(emitNull bool) string {
+	if !emitNull && !v.IsClass() && isValueType(v.Typ, v.DBType) {
+		return v.Typ + "?"
+	}
+	return v.EmitReturnType(emitNull)
+}
+
 func (v QueryValue) Pair() string {
 	log.Println("Arg value pair: ", v)
 	if v.isEmpty() {
@@ -81,7 +106,7 @@ func (v QueryValue) Pair() string {
 	var out []string
 	if !v.EmitClass() && v.IsClass() {
 		for _, f := range v.Class.Members {
-			out = append(out, f.Type+" "+strings.ToLower(f.Name))
+			out = append(out, f.Type+" "+f.ArgName)
 		}
 
 		return strings.Join(out, ", ")
@@ -90,17 +115,104 @@ func (v QueryValue) Pair() string {
 	return v.Type() + " " + v.Name
 }
 
+// DocParam is a method parameter as documented with a <param> tag.
+type DocParam struct {
+	Name string
+	Doc  string
+}
+
+func newDocParam(name, doc string) DocParam {
+	// Doc comments refer to escaped keywords without their @
+	return DocParam{Name: strings.TrimPrefix(name, "@"), Doc: doc}
+}
+
+// DocParams lists the method parameters the value is passed as, described
+// by their column comments where the schema has them.
+func (v QueryValue) DocParams() []DocParam {
+	if v.isEmpty() {
+		return nil
+	}
+	if v.EmitClass() {
+		return []DocParam{newDocParam(v.Name, "The parameters of the query.")}
+	}
+	if v.IsClass() {
+		var params []DocParam
+		for _, m := range v.UniqueMembers() {
+			params = append(params, newDocParam(m.ArgName, columnDoc(m.Column, m.DBName)))
+		}
+		return params
+	}
+	return []DocParam{newDocParam(v.Name, columnDoc(v.Column, v.DBName))}
+}
+
+func columnDoc(c *plugin.Column, name string) string {
+	if c != nil && c.Comment != "" {
+		return strings.TrimSpace(c.Comment)
+	}
+	return "Value for " + name + "."
+}
+
 func (v QueryValue) UniqueMembers() []ClassMember {
-	seen := map[string]struct{}{}
-	members := make([]ClassMember, 0, len(v.Class.Members))
+	return v.Class.UniqueMembers()
+}
 
-	for _, member := range v.Class.Members {
-		if _, found := seen[member.Name]; found {
+// ValueColumn is a database column a query value is passed as or read from.
+type ValueColumn struct {
+	DBName string
+	Type   string
+}
+
+// Columns lists the database columns of the value in order, with the
+// columns of embedded tables in place of their member.
+func (v QueryValue) Columns() []ValueColumn {
+	if v.isEmpty() {
+		return nil
+	}
+	if !v.IsClass() {
+		return []ValueColumn{{DBName: v.DBName, Type: v.Typ}}
+	}
+
+	var columns []ValueColumn
+	for _, m := range v.UniqueMembers() {
+		if m.Embed != nil {
+			for _, e := range m.Embed.Members {
+				columns = append(columns, ValueColumn{DBName: e.DBName, Type: e.Type})
+			}
 			continue
 		}
-		seen[member.Name] = struct{}{}
-		members = append(members, member)
+		columns = append(columns, ValueColumn{DBName: m.DBName, Type: m.Type})
 	}
+	return columns
+}
 
-	return members
+// ParamTypes lists the C# types of the method parameters the value is
+// passed as, in the order of Pair.
+func (v QueryValue) ParamTypes() []string {
+	if v.isEmpty() {
+		return nil
+	}
+	if !v.EmitClass() && v.IsClass() {
+		var types []string
+		for _, f := range v.Class.Members {
+			types = append(types, f.Type)
+		}
+		return types
+	}
+	return []string{v.Type()}
+}
+
+// ParamNames lists the names of the method parameters the value is passed
+// as, in the order of Pair.
+func (v QueryValue) ParamNames() []string {
+	if v.isEmpty() {
+		return nil
+	}
+	if !v.EmitClass() && v.IsClass() {
+		var names []string
+		for _, f := range v.Class.Members {
+			names = append(names, f.ArgName)
+		}
+		return names
+	}
+	return []string{v.Name}
 }
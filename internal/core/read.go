@@ -0,0 +1,114 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+)
+
+// typedGetters are the NpgsqlDataReader getters of the C# types that have
+// one, which avoid the generic dispatch of GetFieldValue<T>.
+var typedGetters = map[string]string{
+	"bool":     "GetBoolean",
+	"short":    "GetInt16",
+	"int":      "GetInt32",
+	"long":     "GetInt64",
+	"float":    "GetFloat",
+	"double":   "GetDouble",
+	"decimal":  "GetDecimal",
+	"string":   "GetString",
+	"Guid":     "GetGuid",
+	"DateTime": "GetDateTime",
+}
+
+// valueTypes are the C# value types PostgresType maps to, besides enums.
+var valueTypes = map[string]bool{
+	"bool":                              true,
+	"short":                             true,
+	"int":                               true,
+	"long":                              true,
+	"uint":                              true,
+	"float":                             true,
+	"double":                            true,
+	"decimal":                           true,
+	"Guid":                              true,
+	"DateTime":                          true,
+	"DateTimeOffset":                    true,
+	"TimeSpan":                          true,
+	"(System.Net.IPAddress, int)":       true,
+	"NpgsqlTypes.NpgsqlInterval":        true,
+	"NpgsqlTypes.NpgsqlRange<DateTime>": true,
+	"NpgsqlTypes.NpgsqlRange<Decimal>":  true,
+	"NpgsqlTypes.NpgsqlRange<int>":      true,
+	"NpgsqlTypes.NpgsqlRange<long>":     true,
+	"NpgsqlTypes.NpgsqlBox":             true,
+	"NpgsqlTypes.NpgsqlCircle":          true,
+	"NpgsqlTypes.NpgsqlLine":            true,
+	"NpgsqlTypes.NpgsqlLSeg":            true,
+	"NpgsqlTypes.NpgsqlPoint":           true,
+}
+
+// isValueType reports whether typ is a C# value type that cannot hold null,
+// which is the case of the generated enums too.
+func isValueType(typ string, dbType DBType) bool {
+	if strings.HasSuffix(typ, "?") || strings.HasSuffix(typ, "[]") {
+		return false
+	}
+	return valueTypes[typ] || dbType.IsEnum && typ != "string" && typ != "object"
+}
+
+// ReadExpr returns the C# expression reading a value of type typ from the
+// column at ordinal of reader. Columns that may be NULL are checked first
+// and read as null when typ is nullable, or as its default otherwise, as
+// for a value type without emit_null_ops.
+func ReadExpr(typ string, col *plugin.Column, ordinal string) string {
+	base := strings.TrimSuffix(typ, "?")
+	get := fmt.Sprintf("reader.GetFieldValue<%s>(%s)", base, ordinal)
+	if getter, found := typedGetters[base]; found {
+		get = fmt.Sprintf("reader.%s(%s)", getter, ordinal)
+	}
+
+	if col != nil && col.NotNull {
+		return get
+	}
+
+	null := "default"
+	if strings.HasSuffix(typ, "?") {
+		null = "null"
+	}
+	return fmt.Sprintf("reader.IsDBNull(%s) ? %s : %s", ordinal, null, get)
+}
+
+// Read returns the expression reading the member from the column at
+// ordinal of reader. The columns of a query are known when generating
+// it, so the ordinal is a constant.
+func (m ClassMember) Read(ordinal int) string {
+	return ReadExpr(m.Type, m.Column, strconv.Itoa(ordinal))
+}
+
+// Read returns the expression reading a single column value from reader.
+func (v QueryValue) Read() string {
+	return ReadExpr(v.Typ, v.Column, "0")
+}
+
+// EmbedAbsent returns the C# condition that is true when the row of the
+// embedded member m is absent from the reader, or "" when it is always
+// present. A row is absent when one of its NOT NULL columns is NULL, or
+// all of its columns are when it has none.
+func (m ClassMember) EmbedAbsent() string {
+	if m.Embed == nil || !m.Optional {
+		return ""
+	}
+
+	var checks []string
+	for i, embedded := range m.Embed.Members {
+		check := "reader.IsDBNull(" + strconv.Itoa(m.Ordinal+i) + ")"
+		if embedded.Column != nil && embedded.Column.NotNull {
+			return check
+		}
+		checks = append(checks, check)
+	}
+	return strings.Join(checks, " && ")
+}
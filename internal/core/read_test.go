@@ -0,0 +1,95 @@
+package core
+
+import (
+	"testing"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+)
+
+func TestReadExpr(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  string
+		col  *plugin.Column
+		want string
+	}{
+		{
+			name: "not null",
+			typ:  "int",
+			col:  &plugin.Column{NotNull: true},
+			want: "reader.GetInt32(o)",
+		},
+		{
+			name: "nullable",
+			typ:  "int?",
+			col:  &plugin.Column{},
+			want: "reader.IsDBNull(o) ? null : reader.GetInt32(o)",
+		},
+		{
+			name: "value type without null operators",
+			typ:  "DateTime",
+			col:  &plugin.Column{},
+			want: "reader.IsDBNull(o) ? default : reader.GetDateTime(o)",
+		},
+		{
+			name: "enum without null operators",
+			typ:  "UserStatus",
+			col:  &plugin.Column{},
+			want: "reader.IsDBNull(o) ? default : reader.GetFieldValue<UserStatus>(o)",
+		},
+		{
+			name: "nullable enum",
+			typ:  "UserStatus?",
+			col:  &plugin.Column{},
+			want: "reader.IsDBNull(o) ? null : reader.GetFieldValue<UserStatus>(o)",
+		},
+		{
+			name: "reference type without null operators",
+			typ:  "string",
+			col:  &plugin.Column{},
+			want: "reader.IsDBNull(o) ? default : reader.GetString(o)",
+		},
+		{
+			name: "array",
+			typ:  "int[]",
+			col:  &plugin.Column{IsArray: true},
+			want: "reader.IsDBNull(o) ? default : reader.GetFieldValue<int[]>(o)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReadExpr(tt.typ, tt.col, "o"); got != tt.want {
+				t.Errorf("ReadExpr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbedAbsent(t *testing.T) {
+	user := &Class{Name: "User", Members: []ClassMember{
+		{Name: "ID", Type: "int", Column: &plugin.Column{Name: "id", NotNull: true}},
+		{Name: "Name", Type: "string", Column: &plugin.Column{Name: "name"}},
+	}}
+	profile := &Class{Name: "Profile", Members: []ClassMember{
+		{Name: "Bio", Type: "string", Column: &plugin.Column{Name: "bio"}},
+		{Name: "URL", Type: "string", Column: &plugin.Column{Name: "url"}},
+	}}
+
+	tests := []struct {
+		name   string
+		member ClassMember
+		want   string
+	}{
+		{name: "not null column", member: ClassMember{Embed: user, Optional: true, Ordinal: 2}, want: "reader.IsDBNull(2)"},
+		{name: "nullable columns", member: ClassMember{Embed: profile, Optional: true, Ordinal: 1}, want: "reader.IsDBNull(1) && reader.IsDBNull(2)"},
+		{name: "always present", member: ClassMember{Embed: user, Ordinal: 2}},
+		{name: "not embedded", member: ClassMember{Optional: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.member.EmbedAbsent(); got != tt.want {
+				t.Errorf("EmbedAbsent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,14 +4,15 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
-	"unicode"
-	"unicode/utf8"
 
 	plugin "github.com/tabbed/sqlc-go/codegen"
 
@@ -21,21 +22,145 @@ import (
 var version string
 
 type TemplateCtx struct {
-	EmitAsync     bool
-	EmitNulls     bool
-	SqlcVersion   string
-	CsGenVersion  string
-	Namespace     string
-	QueryFileName string
-	CodeQueries   []core.Query
-	Enums         []core.Enum
-	Classes       []core.Class
+	Driver         string
+	NpgsqlFeatures bool
+	LegacyNpgsql   bool
+	EmitServices   bool
+	CsharpVersion  int
+	EmitAsync      bool
+	EmitSync       bool
+	ConfigureAwait bool
+	ValueTask      bool
+	EmitNulls      bool
+	EmitTracing    bool
+	TraceStatement bool
+	EmitHooks      bool
+	// DBSystem tags traced activities with the database they run on. The
+	// sqlc engine names are also the OpenTelemetry db.system values.
+	DBSystem       string
+	EmitInterface  bool
+	EmitFakes      bool
+	SqlcVersion    string
+	CsGenVersion   string
+	Namespace      string
+	QueryFileName  string
+	QueryClassName string
+	CodeQueries    []core.Query
+	Enums          []core.Enum
+	Classes        []core.Class
+	SharedClasses  []core.Class
+	UnnestClasses  bool
+	SplitFiles     bool
+
+	// QueryClasses maps every SQL file to the static class of its queries.
+	QueryClasses map[string]string
+
+	// Tables describe the catalog in the schema metadata file.
+	Tables []TableMetadata
+
+	// TargetFramework and Packages fill in the project file.
+	TargetFramework string
+	Packages        []Package
+
+	// Enum and Class hold the type rendered by the single type templates.
+	Enum  *core.Enum
+	Class *core.Class
 }
 
 func (t *TemplateCtx) OutputQuery(sourceName string) bool {
 	return t.QueryFileName == StripExtension(sourceName)
 }
 
+// SQLLiteral renders the text of a query, headed by its name comment, as a
+// C# string literal. C# 11 and later get a raw string literal, older
+// versions a verbatim one with its quotes doubled.
+func (t *TemplateCtx) SQLLiteral(q core.Query) string {
+	text := "-- name: " + q.MethodName + " " + q.Cmd + "\n" + q.SQL
+	if t.CsharpVersion >= 11 {
+		return RawStringLiteral(text, "        ")
+	}
+	return VerbatimStringLiteral(text)
+}
+
+// QueryClass returns the static class holding the queries of a SQL file.
+func (t *TemplateCtx) QueryClass(sourceName string) string {
+	return t.QueryClasses[sourceName]
+}
+
+// OuterType returns typ, the type of a query value, as named outside the
+// query class, which declares the Params and Row classes unless they are
+// unnested.
+func (t *TemplateCtx) OuterType(sourceName string, v core.QueryValue, typ string) string {
+	if v.DeclareClass() && !t.UnnestClasses {
+		return t.QueryClass(sourceName) + "." + typ
+	}
+	return typ
+}
+
+// ResultType returns the type the method of a query returns, before any
+// Task wrapping, as named outside the query class.
+func (t *TemplateCtx) ResultType(q core.Query) string {
+	switch q.Cmd {
+	case ":one":
+		return t.OuterType(q.SourceName, q.Ret, q.Ret.OneReturnType(t.EmitNulls))
+	case ":many":
+		return "List<" + t.OuterType(q.SourceName, q.Ret, q.Ret.EmitReturnType(t.EmitNulls)) + ">"
+	default:
+		return "int"
+	}
+}
+
+// MethodResult returns the type the method m returns, wrapped in its Task,
+// as named outside the query class.
+func (t *TemplateCtx) MethodResult(m Method) string {
+	if task := m.Task(); task != "" {
+		return task + "<" + t.ResultType(m.Query) + ">"
+	}
+	return t.ResultType(m.Query)
+}
+
+// QuerierSource is the parameter the Querier class passes to every query
+// method, which it is constructed with.
+type QuerierSource struct {
+	Type string
+	Name string
+}
+
+// QuerierSource returns what the Querier class runs the queries on with
+// the driver of t.
+func (t *TemplateCtx) QuerierSource() QuerierSource {
+	switch {
+	case t.Driver == core.DriverDapper:
+		return QuerierSource{Type: "IDbConnection", Name: "conn"}
+	case t.Driver == core.DriverADO:
+		return QuerierSource{Type: "DbConnection", Name: "conn"}
+	case t.LegacyNpgsql:
+		return QuerierSource{Type: "string", Name: "connectionString"}
+	default:
+		return QuerierSource{Type: "NpgsqlDataSource", Name: "dbSource"}
+	}
+}
+
+// ParamTypes returns the types of the method parameters of a query, as
+// named outside the query class.
+func (t *TemplateCtx) ParamTypes(q core.Query) []string {
+	if q.Arg.EmitClass() {
+		return []string{t.OuterType(q.SourceName, q.Arg, q.Arg.Type())}
+	}
+	return q.Arg.ParamTypes()
+}
+
+// OuterPair is Pair for the method parameters of a query with their types
+// named as outside the query class.
+func (t *TemplateCtx) OuterPair(q core.Query) string {
+	types, names := t.ParamTypes(q), q.Arg.ParamNames()
+	params := make([]string, len(types))
+	for i := range types {
+		params[i] = types[i] + " " + names[i]
+	}
+	return strings.Join(params, ", ")
+}
+
 func (t *TemplateCtx) ClassName() {
 
 }
@@ -45,17 +170,15 @@ func Generate(ctx context.Context, req *plugin.Request) (*plugin.Response, error
 		version = "0.1.0"
 	}
 
-	var conf core.Config
-	if len(req.PluginOptions) > 0 {
-		if err := json.Unmarshal(req.PluginOptions, &conf); err != nil {
-			return nil, err
-		}
+	conf, err := core.ParseConfig(req.PluginOptions)
+	if err != nil {
+		return nil, err
 	}
 
 	if conf.LogFile != "" {
 		f, err := os.OpenFile(conf.LogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 		if err != nil {
-			log.Fatalf("Error opening log file: %v", err)
+			return nil, fmt.Errorf("invalid log_file %q: %w", conf.LogFile, err)
 		}
 
 		defer f.Close()
@@ -63,84 +186,239 @@ func Generate(ctx context.Context, req *plugin.Request) (*plugin.Response, error
 	}
 
 	log.Println("Beginning generation with config: ", conf)
-	enums := core.BuildEnums(req)
-	classes := core.BuildClasses(req, conf)
+	// Every problem with the schema and queries is reported at once
+	var errs []error
+	enums := core.BuildEnums(req, conf)
+	classes, err := core.BuildClasses(req, conf)
+	if err != nil {
+		errs = append(errs, err)
+	}
 	queries, err := core.BuildQueries(req, conf, classes)
 	log.Println("queries built: ", queries)
 	if err != nil {
-		return nil, err
+		errs = append(errs, err)
+	}
+
+	if conf.Driver == core.DriverDapper {
+		for i := range queries {
+			if err := core.UseNamedParameters(&queries[i]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if conf.Driver == core.DriverADO && !conf.EmitNpgsqlFeatures {
+		for _, q := range queries {
+			if err := core.CheckADOEnums(q); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var shared []core.Class
+	if conf.EmitSharedClasses {
+		shared = core.CoalesceClasses(queries)
 	}
 
 	tctx := TemplateCtx{
-		EmitAsync:    conf.EmitAsync,
-		EmitNulls:    conf.EmitNullOperators,
-		SqlcVersion:  req.SqlcVersion,
-		CsGenVersion: version,
-		Namespace:    conf.Namespace,
-		Classes:      classes,
-		Enums:        enums,
+		Driver:         conf.Driver,
+		NpgsqlFeatures: conf.Driver != core.DriverADO || conf.EmitNpgsqlFeatures,
+		LegacyNpgsql:   conf.LegacyNpgsql(),
+		EmitServices:   conf.EmitDependencyInjection,
+		CsharpVersion:  conf.CsharpVersion,
+		EmitAsync:      conf.EmitAsync,
+		EmitSync:       conf.EmitSync || !conf.EmitAsync,
+		ConfigureAwait: conf.EmitConfigureAwait,
+		ValueTask:      conf.EmitValueTask,
+		EmitNulls:      conf.EmitNullOperators,
+		EmitTracing:    conf.EmitTracing,
+		TraceStatement: conf.EmitTracingStatement,
+		EmitHooks:      conf.EmitDiagnostics,
+		DBSystem:       req.Settings.Engine,
+		EmitInterface:  conf.EmitsInterface(),
+		EmitFakes:      conf.EmitFakes,
+		SqlcVersion:    req.SqlcVersion,
+		CsGenVersion:   version,
+		Namespace:      conf.Namespace,
+		Classes:        classes,
+		SharedClasses:  shared,
+		CodeQueries:    queries,
+		UnnestClasses:  conf.UnnestQueryClasses,
+		SplitFiles:     conf.OutputLayout == core.LayoutPerType,
+		Enums:          enums,
 	}
 
 	funcMap := template.FuncMap{
-		"comment":   DoubleSlashComment,
-		"classname": RawClassName,
+		"doc":   DocComment,
+		"xml":   XMLEscape,
+		"trim":  strings.TrimSpace,
+		"lines": func(s string) []string { return strings.Split(s, "\n") },
+		"add":   func(a, b int) int { return a + b },
+		"str":   StringLiteral,
+		"join":  strings.Join,
+	}
+
+	var tmpl *template.Template
+	funcMap["include"] = func(name string, data interface{}) (string, error) {
+		var b bytes.Buffer
+		err := tmpl.ExecuteTemplate(&b, name, data)
+		return b.String(), err
+	}
+	funcMap["indent"] = func(indent, s string) string {
+		return strings.ReplaceAll(s, "\n", "\n"+indent)
 	}
 
-	tmpl := template.Must(template.New("table").
+	tmpl, err = template.New("table").
 		Funcs(funcMap).
 		ParseFS(
 			templates,
-			"templates/*/*.tmpl",
-		),
-	)
+			"templates/common/*.tmpl",
+			"templates/"+tctx.Driver+"/*.tmpl",
+		)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the %s driver templates: %w", tctx.Driver, err)
+	}
 
 	output := map[string]string{}
 
-	execute := func(name, templateName string) error {
+	render := func(templateName string) (string, error) {
 		var b bytes.Buffer
 		w := bufio.NewWriter(&b)
 
-		tctx.QueryFileName = name
-		tctx.CodeQueries = queries
 		err := tmpl.ExecuteTemplate(w, templateName, &tctx)
 		w.Flush()
 		if err != nil {
-			return err
+			return "", err
+		}
+
+		return b.String(), nil
+	}
+
+	// Files differing only in case are duplicates too, as they overwrite
+	// each other on case-insensitive file systems
+	generated := map[string]string{}
+	execute := func(name, templateName, what string) error {
+		key := strings.ToLower(name + ".cs")
+		if other, found := generated[key]; found {
+			return fmt.Errorf("generating %s.cs for %s: the file is already generated for %s", name, what, other)
 		}
+		generated[key] = what
 
-		code := b.Bytes()
+		code, err := render(templateName)
+		if err != nil {
+			return fmt.Errorf("generating %s.cs: %w", name, err)
+		}
 		// TODO: implement auto formatting using dotnet tools
 
-		output[name] = string(code)
+		output[name+".cs"] = code
 		return nil
 	}
 
-	modelName := "Models"
 	log.Println("Creating models for: ", tctx.Classes)
-	if err := execute(modelName, "modelsFile"); err != nil {
-		return nil, err
+	if tctx.SplitFiles {
+		for i := range enums {
+			tctx.Enum = &enums[i]
+			if err := execute(path.Join(conf.ModelsFolder, enums[i].Name), "enumFile", "enum "+enums[i].Name); err != nil {
+				return nil, err
+			}
+		}
+		for i := range classes {
+			tctx.Class = &classes[i]
+			if err := execute(path.Join(conf.ModelsFolder, classes[i].Name), "modelFile", "model "+classes[i].Name); err != nil {
+				return nil, err
+			}
+		}
+		for i := range shared {
+			tctx.Class = &shared[i]
+			if err := execute(path.Join(conf.ModelsFolder, shared[i].Name), "queryClassFile", "shared class "+shared[i].Name); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if err := execute(path.Join(conf.ModelsFolder, conf.ModelsFileName), "modelsFile", "the models"); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.EmitSchemaMetadata {
+		tctx.Tables = SchemaMetadata(classes)
+		if err := execute(path.Join(conf.ModelsFolder, "SchemaMetadata"), "schemaMetadataFile", "the schema metadata"); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := execute("DbHelper", "helpersFile"); err != nil {
+	if err := execute(conf.HelpersFileName, "helpersFile", "the helpers"); err != nil {
 		return nil, err
 	}
 
-	files := map[string]struct{}{}
+	files := map[string]string{}
 	for _, gq := range queries {
-		files[gq.SourceName] = struct{}{}
+		files[gq.SourceName] = ""
+	}
+	if err := nameQueryClasses(files, &tctx, &conf); err != nil {
+		return nil, err
 	}
 
-	for source := range files {
+	tctx.QueryClasses = files
+	if conf.EmitQueryRegistry {
+		if err := execute(path.Join(conf.QueriesFolder, queryRegistryClass), "queryRegistryFile", "the query registry"); err != nil {
+			return nil, err
+		}
+	}
+
+	if tctx.EmitInterface {
+		if err := execute(path.Join(conf.QueriesFolder, querierClass), "querierFile", "the querier"); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.EmitFakes {
+		if err := execute(path.Join(conf.QueriesFolder, fakeQuerierClass), "fakeQuerierFile", "the fake querier"); err != nil {
+			return nil, err
+		}
+	}
+
+	for source, className := range files {
 		name := StripExtension(source)
-		if err := execute(name, "queriesFile"); err != nil {
+		tctx.QueryFileName = name
+		tctx.QueryClassName = className
+		if err := execute(path.Join(conf.QueriesFolder, name), "queriesFile", "the queries of "+source); err != nil {
 			return nil, err
 		}
 	}
 
+	// Unnested Params and Row classes get a file of their own
+	if tctx.SplitFiles && tctx.UnnestClasses {
+		for _, q := range queries {
+			for _, v := range []core.QueryValue{q.Arg, q.Ret} {
+				if !v.DeclareClass() {
+					continue
+				}
+				tctx.Class = v.Class
+				if err := execute(path.Join(conf.QueriesFolder, v.Class.Name), "queryClassFile", "query class "+v.Class.Name); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if conf.EmitProjectFile {
+		tctx.TargetFramework = conf.TargetFramework
+		tctx.Packages = ProjectPackages(&tctx, &conf)
+
+		code, err := render("projectFile")
+		if err != nil {
+			return nil, fmt.Errorf("generating %s.csproj: %w", conf.ProjectName, err)
+		}
+		output[conf.ProjectName+".csproj"] = code
+	}
+
 	resp := plugin.CodeGenResponse{}
 	for filename, code := range output {
 		resp.Files = append(resp.Files, &plugin.File{
-			Name:     filename + ".cs",
+			Name:     filename,
 			Contents: []byte(code),
 		})
 	}
@@ -148,26 +426,190 @@ func Generate(ctx context.Context, req *plugin.Request) (*plugin.Response, error
 	return &resp, nil
 }
 
-func DoubleSlashComment(s string) string {
-	return "// " + strings.ReplaceAll(s, "\n", "\n// ")
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// XMLEscape escapes text for use inside an XML doc comment.
+func XMLEscape(s string) string {
+	return xmlEscaper.Replace(s)
+}
+
+// DocComment renders s as an XML doc comment summary. Every line after the
+// first is prefixed with indent, the template writes the first one.
+func DocComment(indent, s string) string {
+	var b strings.Builder
+	b.WriteString("/// <summary>")
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		b.WriteString("\n" + indent + "/// " + XMLEscape(strings.TrimSpace(line)))
+	}
+	b.WriteString("\n" + indent + "/// </summary>")
+	return b.String()
+}
+
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// StringLiteral quotes s as a regular C# string literal.
+func StringLiteral(s string) string {
+	return `"` + stringEscaper.Replace(s) + `"`
+}
+
+// VerbatimStringLiteral quotes s as a C# verbatim string, in which the only
+// character needing an escape is the double quote.
+func VerbatimStringLiteral(s string) string {
+	return `@"` + strings.ReplaceAll(s, `"`, `""`) + `"`
 }
 
-func RawClassName(name string) string {
-	out := ""
-	for _, p := range strings.Split(name, "_") {
-		if p == "id" {
-			out += "ID"
+// RawStringLiteral quotes s as a multi-line C# 11 raw string literal with
+// every line prefixed by indent. The delimiter is made one quote longer than
+// the longest run of quotes in s, so the content never needs escaping.
+func RawStringLiteral(s, indent string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '"' {
+			run++
+			if run > longest {
+				longest = run
+			}
 		} else {
-			out += strings.Title(p)
+			run = 0
 		}
 	}
+	delim := `"""`
+	if longest >= len(delim) {
+		delim = strings.Repeat(`"`, longest+1)
+	}
 
-	r, _ := utf8.DecodeRuneInString(out)
-	if unicode.IsDigit(r) {
-		return "_" + out
-	} else {
-		return out
+	var b strings.Builder
+	b.WriteString(delim)
+	for _, line := range strings.Split(s, "\n") {
+		b.WriteString("\n")
+		if strings.TrimSpace(line) != "" {
+			b.WriteString(indent)
+			b.WriteString(strings.TrimRight(line, "\r"))
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(indent)
+	b.WriteString(delim)
+	return b.String()
+}
+
+func RawClassName(name string, conf *core.Config) string {
+	return core.PascalCase(name, conf)
+}
+
+// nameQueryClasses fills in the static class name for every SQL file in
+// files using the query_class_name template, and reports the names that
+// collide with each other or with a type declared in the namespace.
+func nameQueryClasses(files map[string]string, tctx *TemplateCtx, conf *core.Config) error {
+	pattern := conf.QueryClassName
+	tmpl, err := template.New("query_class_name").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid query_class_name %q: %w", pattern, err)
+	}
+
+	// Types declared in the namespace may not share a name either
+	types := map[string]string{}
+	var clashes []string
+	declare := func(name, kind string) {
+		if other, found := types[name]; found {
+			clashes = append(clashes, fmt.Sprintf("%s %s collides with %s %s", kind, name, other, name))
+			return
+		}
+		types[name] = kind
+	}
+
+	for _, e := range tctx.Enums {
+		declare(e.Name, "enum")
+	}
+	for _, c := range tctx.Classes {
+		declare(c.Name, "model")
+	}
+	for _, c := range tctx.SharedClasses {
+		declare(c.Name, "shared class")
+	}
+	if conf.EmitSchemaMetadata {
+		declare(schemaMetadataClass, "the schema metadata class")
+	}
+	if conf.EmitQueryRegistry {
+		declare(queryRegistryClass, "the query registry class")
+	}
+	if tctx.EmitInterface {
+		declare(querierInterface, "the querier interface")
+		declare(querierClass, "the querier class")
+	}
+	if conf.EmitFakes {
+		declare(fakeQuerierClass, "the fake querier class")
+	}
+	if tctx.UnnestClasses {
+		for _, q := range tctx.CodeQueries {
+			for _, v := range []core.QueryValue{q.Arg, q.Ret} {
+				if v.DeclareClass() {
+					declare(v.Class.Name, "query class")
+				}
+			}
+		}
+	}
+	if len(clashes) > 0 {
+		return fmt.Errorf("generated type names collide, rename the tables or enums with the rename setting: %s", strings.Join(clashes, "; "))
+	}
+	if conf.EmitFakes {
+		if err := checkFakeMembers(tctx); err != nil {
+			return err
+		}
+	}
+
+	sources := make([]string, 0, len(files))
+	for source := range files {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var collisions []string
+	for _, source := range sources {
+		var b strings.Builder
+		data := struct{ File string }{File: RawClassName(StripExtension(source), conf)}
+		if err := tmpl.Execute(&b, data); err != nil {
+			return fmt.Errorf("invalid query_class_name %q: %w", pattern, err)
+		}
+
+		name := core.SanitizeIdentifier(b.String())
+		if kind, found := types[name]; found {
+			collisions = append(collisions, fmt.Sprintf("class %s for %s collides with %s %s", name, source, kind, name))
+		}
+		types[name] = "the query class of " + source
+		files[source] = name
+	}
+
+	if len(collisions) > 0 {
+		return fmt.Errorf("query class names collide, set query_class_name to e.g. \"{{.File}}Queries\": %s", strings.Join(collisions, "; "))
+	}
+	return nil
+}
+
+// checkFakeMembers reports the query methods and handler properties of
+// the fake querier that collide with each other, with its own members or
+// with the class itself.
+func checkFakeMembers(tctx *TemplateCtx) error {
+	members := map[string]string{fakeQuerierClass: "the fake querier class"}
+	for _, name := range fakeQuerierMembers {
+		members[name] = "FakeQuerier." + name
+	}
+
+	var collisions []string
+	for _, m := range tctx.AllMethods() {
+		for _, name := range []string{m.Name(), m.Name() + "Handler"} {
+			if other, found := members[name]; found {
+				collisions = append(collisions, fmt.Sprintf("%s of query %s collides with %s", name, m.MethodName, other))
+				continue
+			}
+			members[name] = "the member of query " + m.MethodName
+		}
+	}
+
+	if len(collisions) > 0 {
+		return fmt.Errorf("fake querier members collide, rename the queries: %s", strings.Join(collisions, "; "))
 	}
+	return nil
 }
 
 func StripExtension(val string) string {
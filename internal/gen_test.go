@@ -0,0 +1,612 @@
+package csharp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	plugin "github.com/tabbed/sqlc-go/codegen"
+
+	"github.com/hyperbeam/sqlc-gen-cs/internal/core"
+)
+
+// generateRequest returns a request for a schema with the given tables,
+// each with an id column, and a query on the first one in users.sql.
+func generateRequest(options string, tables ...string) *plugin.Request {
+	schema := &plugin.Schema{Name: "public"}
+	for _, name := range tables {
+		rel := &plugin.Identifier{Schema: "public", Name: name}
+		schema.Tables = append(schema.Tables, &plugin.Table{
+			Rel: rel,
+			Columns: []*plugin.Column{
+				{Name: "id", NotNull: true, Type: &plugin.Identifier{Name: "int4"}, Table: rel},
+			},
+		})
+	}
+
+	id := schema.Tables[0].Columns[0]
+	return &plugin.Request{
+		Settings:      &plugin.Settings{Engine: "postgresql"},
+		PluginOptions: []byte(options),
+		Catalog:       &plugin.Catalog{DefaultSchema: "public", Schemas: []*plugin.Schema{schema}},
+		Queries: []*plugin.Query{{
+			Name:     "GetID",
+			Cmd:      ":one",
+			Filename: "users.sql",
+			Text:     "SELECT id FROM " + tables[0],
+			Columns:  []*plugin.Column{id},
+		}},
+	}
+}
+
+// generateFiles generates the code for req and returns the contents of
+// the files by name.
+func generateFiles(t *testing.T, req *plugin.Request) map[string]string {
+	t.Helper()
+	resp, err := Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	files := make(map[string]string, len(resp.Files))
+	for _, f := range resp.Files {
+		files[f.Name] = string(f.Contents)
+	}
+	return files
+}
+
+// checkFile reports a missing file and every line it does not contain.
+func checkFile(t *testing.T, files map[string]string, name string, lines ...string) {
+	t.Helper()
+	contents, ok := files[name]
+	if !ok {
+		t.Errorf("Generate() has no file %s", name)
+		return
+	}
+	for _, line := range lines {
+		if !strings.Contains(contents, line) {
+			t.Errorf("%s does not contain %q", name, line)
+		}
+	}
+}
+
+func TestGenerateCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		tables  []string
+		query   string
+		wantErr string
+	}{
+		{
+			name:    "helpers file named as the models file",
+			options: `{"namespace": "App.Db", "helpers_file_name": "Models"}`,
+			tables:  []string{"users"},
+			wantErr: "Models.cs for the helpers: the file is already generated for the models",
+		},
+		{
+			name:    "per type model named as a query file",
+			options: `{"namespace": "App.Db", "output_layout": "per_type", "query_class_name": "{{.File}}Queries", "emit_exact_table_names": true}`,
+			tables:  []string{"users"},
+			wantErr: "users.cs for the queries of users.sql: the file is already generated for model Users",
+		},
+		{
+			name:    "table named as the fake querier",
+			options: `{"namespace": "App.Db", "emit_fakes": true}`,
+			tables:  []string{"fake_queriers"},
+			wantErr: "the fake querier class FakeQuerier collides with model FakeQuerier",
+		},
+		{
+			name:    "table named as the querier",
+			options: `{"namespace": "App.Db", "emit_interface": true, "query_class_name": "{{.File}}Queries"}`,
+			tables:  []string{"users", "queriers"},
+			wantErr: "the querier class Querier collides with model Querier",
+		},
+		{
+			name:    "table named as the query registry",
+			options: `{"namespace": "App.Db", "emit_query_registry": true, "query_class_name": "{{.File}}Queries"}`,
+			tables:  []string{"users", "query_registry"},
+			wantErr: "the query registry class QueryRegistry collides with model QueryRegistry",
+		},
+		{
+			name:    "table named as the schema metadata",
+			options: `{"namespace": "App.Db", "emit_schema_metadata": true, "query_class_name": "{{.File}}Queries", "emit_exact_table_names": true}`,
+			tables:  []string{"users", "schema_metadata"},
+			wantErr: "the schema metadata class SchemaMetadata collides with model SchemaMetadata",
+		},
+		{
+			name:    "query named as a fake querier member",
+			options: `{"namespace": "App.Db", "emit_fakes": true, "query_class_name": "{{.File}}Queries"}`,
+			tables:  []string{"users"},
+			query:   "Reset",
+			wantErr: "fake querier members collide, rename the queries: Reset of query Reset collides with FakeQuerier.Reset",
+		},
+		{
+			name:    "query named as a fake querier handler",
+			options: `{"namespace": "App.Db", "emit_fakes": true, "query_class_name": "{{.File}}Queries"}`,
+			tables:  []string{"users"},
+			query:   "GetIDHandler",
+			wantErr: "GetIDHandler of query GetIDHandler collides with the member of query GetID",
+		},
+		{
+			name:    "no collision",
+			options: `{"namespace": "App.Db", "output_layout": "per_type", "query_class_name": "{{.File}}Queries"}`,
+			tables:  []string{"accounts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := generateRequest(tt.options, tt.tables...)
+			if tt.query != "" {
+				q := req.Queries[0]
+				req.Queries = append(req.Queries, &plugin.Query{Name: tt.query, Cmd: q.Cmd, Filename: q.Filename, Text: q.Text, Columns: q.Columns})
+			}
+			_, err := Generate(context.Background(), req)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Generate() error = %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("Generate() succeeded, want error %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("Generate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	req := generateRequest(`{"namespace": "App.Db"}`, "users")
+	geometry := &plugin.Column{Name: "area", Type: &plugin.Identifier{Name: "geometry"}}
+	req.Queries = append(req.Queries,
+		&plugin.Query{Name: "GetArea", Cmd: ":one", Filename: "users.sql", Columns: []*plugin.Column{geometry}},
+		&plugin.Query{Name: "FindByArea", Cmd: ":exec", Filename: "users.sql", Params: []*plugin.Parameter{{Number: 1, Column: geometry}}},
+	)
+
+	resp, err := Generate(context.Background(), req)
+	if resp != nil {
+		t.Errorf("Generate() = %d files, want none", len(resp.Files))
+	}
+	if err == nil {
+		t.Fatal("Generate() succeeded, want errors")
+	}
+	for _, want := range []string{
+		"query GetArea in users.sql: column area: no C# type for database type geometry",
+		"query FindByArea in users.sql: parameter $1 (area): no C# type for database type geometry",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Generate() error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestRawStringLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "single line",
+			sql:  "SELECT 1",
+			want: "\"\"\"\n  SELECT 1\n  \"\"\"",
+		},
+		{
+			name: "quotes",
+			sql:  `SELECT "id" FROM users WHERE name = '""'`,
+			want: "\"\"\"\n  SELECT \"id\" FROM users WHERE name = '\"\"'\n  \"\"\"",
+		},
+		{
+			name: "run of three quotes",
+			sql:  `SELECT '"""' AS quotes`,
+			want: "\"\"\"\"\n  SELECT '\"\"\"' AS quotes\n  \"\"\"\"",
+		},
+		{
+			name: "run of five quotes",
+			sql:  `SELECT '"""""' AS quotes, '"""'`,
+			want: "\"\"\"\"\"\"\n  SELECT '\"\"\"\"\"' AS quotes, '\"\"\"'\n  \"\"\"\"\"\"",
+		},
+		{
+			name: "leading and trailing newlines",
+			sql:  "\nSELECT 1\n",
+			want: "\"\"\"\n\n  SELECT 1\n\n  \"\"\"",
+		},
+		{
+			name: "carriage returns",
+			sql:  "SELECT 1\r\nFROM users",
+			want: "\"\"\"\n  SELECT 1\n  FROM users\n  \"\"\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RawStringLiteral(tt.sql, "  "); got != tt.want {
+				t.Errorf("RawStringLiteral() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	q := core.Query{MethodName: "GetUser", Cmd: ":one", SQL: "SELECT \"id\" FROM users\nWHERE name = '\"\"\"'\n"}
+	tests := []struct {
+		version int
+		want    string
+	}{
+		{
+			version: 10,
+			want:    "@\"-- name: GetUser :one\nSELECT \"\"id\"\" FROM users\nWHERE name = '\"\"\"\"\"\"'\n\"",
+		},
+		{
+			version: 11,
+			want:    "\"\"\"\"\n        -- name: GetUser :one\n        SELECT \"id\" FROM users\n        WHERE name = '\"\"\"'\n\n        \"\"\"\"",
+		},
+	}
+	for _, tt := range tests {
+		tctx := TemplateCtx{CsharpVersion: tt.version}
+		if got := tctx.SQLLiteral(q); got != tt.want {
+			t.Errorf("SQLLiteral() with C# %d = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateFakes(t *testing.T) {
+	req := generateRequest(`{"namespace": "App.Db", "emit_fakes": true, "emit_async": true}`, "users")
+	id := req.Queries[0].Columns[0]
+	req.Queries = append(req.Queries, &plugin.Query{
+		Name: "GetByID", Cmd: ":one", Filename: "users.sql",
+		Text:    "SELECT id FROM users WHERE id = $1",
+		Columns: []*plugin.Column{id},
+		Params:  []*plugin.Parameter{{Number: 1, Column: id}},
+	})
+
+	files := generateFiles(t, req)
+	checkFile(t, files, "FakeQuerier.cs",
+		"using System;\nusing System.Collections.Generic;\nusing System.Linq;\nusing System.Threading.Tasks;\n",
+		`calls.Add(new Call("GetID", Array.Empty<object?>()));`,
+		`calls.Add(new Call("GetByID", new object?[] { id }));`,
+	)
+}
+
+func TestGenerateDocs(t *testing.T) {
+	req := generateRequest(`{"namespace": "App.Db"}`, "users")
+	schema := req.Catalog.Schemas[0]
+	schema.Tables[0].Comment = "Registered <users>"
+	schema.Tables[0].Columns[0].Comment = "The id & key"
+	schema.Enums = []*plugin.Enum{{Name: "mood", Vals: []string{"happy"}, Comment: "How a user feels"}}
+	id := schema.Tables[0].Columns[0]
+	req.Queries = append(req.Queries, &plugin.Query{
+		Name: "GetByID", Cmd: ":one", Filename: "users.sql",
+		Text:     "SELECT id FROM users WHERE id = $1",
+		Comments: []string{" Fetches a user by <id>"},
+		Columns:  []*plugin.Column{id},
+		Params:   []*plugin.Parameter{{Number: 1, Column: id}},
+	})
+
+	files := generateFiles(t, req)
+	checkFile(t, files, "Models.cs",
+		"/// <summary>\n/// How a user feels\n/// </summary>\npublic enum Mood {",
+		"/// <summary>\n/// Registered &lt;users&gt;\n/// </summary>\npublic class User {",
+		"    /// <summary>\n    /// The id &amp; key\n    /// </summary>\n    public int ID { get; set; }",
+	)
+	checkFile(t, files, "users.cs",
+		"    /// <summary>\n    /// Fetches a user by &lt;id&gt;\n    /// <c>-- name: GetByID :one</c>\n    /// </summary>",
+		`    /// <param name="id">The id &amp; key</param>`,
+	)
+}
+
+func TestGenerateOutputLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		files   map[string][]string
+	}{
+		{
+			name:    "combined",
+			options: `{"namespace": "App.Db"}`,
+			files: map[string][]string{
+				"Models.cs": {"public enum Mood {", "public class User {"},
+				"users.cs":  {"public static class Users {", "    public class FindUsersRow {", "    public class FindUsersParams {"},
+			},
+		},
+		{
+			name:    "per type in folders",
+			options: `{"namespace": "App.Db", "output_layout": "per_type", "models_folder": "Models", "queries_folder": "Queries"}`,
+			files: map[string][]string{
+				"Models/Mood.cs":   {"public enum Mood {"},
+				"Models/User.cs":   {"public class User {"},
+				"Queries/users.cs": {"public static class Users {", "    public class FindUsersRow {", "    public class FindUsersParams {"},
+			},
+		},
+		{
+			name:    "per type with unnested query classes",
+			options: `{"namespace": "App.Db", "output_layout": "per_type", "queries_folder": "Queries", "unnest_query_classes": true}`,
+			files: map[string][]string{
+				"Mood.cs":                    {"public enum Mood {"},
+				"User.cs":                    {"public class User {"},
+				"Queries/users.cs":           {"public static class Users {"},
+				"Queries/FindUsersRow.cs":    {"\npublic class FindUsersRow {"},
+				"Queries/FindUsersParams.cs": {"\npublic class FindUsersParams {"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := generateRequest(tt.options, "users")
+			schema := req.Catalog.Schemas[0]
+			schema.Enums = []*plugin.Enum{{Name: "mood", Vals: []string{"happy"}}}
+			id := schema.Tables[0].Columns[0]
+			count := &plugin.Column{Name: "count", NotNull: true, Type: &plugin.Identifier{Name: "int8"}, IsFuncCall: true}
+			req.Queries = append(req.Queries, &plugin.Query{
+				Name: "FindUsers", Cmd: ":many", Filename: "users.sql",
+				Text:    "SELECT id, count(*) OVER () FROM users WHERE id BETWEEN $1 AND $2",
+				Columns: []*plugin.Column{id, count},
+				Params:  []*plugin.Parameter{{Number: 1, Column: id}, {Number: 2, Column: id}},
+			})
+
+			files := generateFiles(t, req)
+			for name, lines := range tt.files {
+				checkFile(t, files, name, lines...)
+			}
+			if len(files) != len(tt.files)+1 {
+				t.Errorf("Generate() = %d files, want %d and the helpers", len(files), len(tt.files))
+			}
+		})
+	}
+}
+
+func TestGenerateProjectFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		file    string
+		lines   []string
+	}{
+		{
+			name:    "defaults",
+			options: `{"namespace": "App.Db", "emit_project_file": true, "driver": "dapper"}`,
+			file:    "App.Db.csproj",
+			lines: []string{
+				"<Project Sdk=\"Microsoft.NET.Sdk\">",
+				"<TargetFramework>net8.0</TargetFramework>",
+				"<RootNamespace>App.Db</RootNamespace>",
+				"<Nullable>enable</Nullable>",
+				"<LangVersion>10</LangVersion>",
+				`<PackageReference Include="Dapper" Version="2.1.35" />`,
+				`<PackageReference Include="Npgsql" Version="8.0.5" />`,
+			},
+		},
+		{
+			name:    "configured",
+			options: `{"namespace": "App.Db", "emit_project_file": true, "project_name": "App.Data", "target_framework": "net6.0", "package_versions": {"Npgsql": "7.0.6"}}`,
+			file:    "App.Data.csproj",
+			lines: []string{
+				"<TargetFramework>net6.0</TargetFramework>",
+				`<PackageReference Include="Npgsql" Version="7.0.6" />`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := generateFiles(t, generateRequest(tt.options, "users"))
+			checkFile(t, files, tt.file, tt.lines...)
+		})
+	}
+}
+
+func TestGenerateSchemaMetadata(t *testing.T) {
+	req := generateRequest(`{"namespace": "App.Db", "emit_schema_metadata": true}`, "users", "blog_posts")
+	posts := req.Catalog.Schemas[0].Tables[1]
+	posts.Columns = append(posts.Columns, &plugin.Column{Name: "user_id", NotNull: true, Type: &plugin.Identifier{Name: "int4"}, Table: posts.Rel})
+
+	files := generateFiles(t, req)
+	checkFile(t, files, "SchemaMetadata.cs",
+		"public static class SchemaMetadata {",
+		"    /// <summary>Table <c>public.users</c>.</summary>\n    public static class User {\n        public const string TableName = \"users\";\n        public const string Schema = \"public\";",
+		"    public static class BlogPost {\n        public const string TableName = \"blog_posts\";",
+		"        public static class Columns {\n            public const string ID = \"id\";\n            public const string UserID = \"user_id\";\n        }",
+	)
+}
+
+func TestGenerateQueryRegistry(t *testing.T) {
+	req := generateRequest(`{"namespace": "App.Db", "emit_query_registry": true}`, "users")
+	id := req.Queries[0].Columns[0]
+	req.Queries = append(req.Queries, &plugin.Query{
+		Name: "DeleteUser", Cmd: ":exec", Filename: "admin.sql",
+		Text:   "DELETE FROM users WHERE id = $1",
+		Params: []*plugin.Parameter{{Number: 1, Column: id}},
+	})
+
+	files := generateFiles(t, req)
+	checkFile(t, files, "QueryRegistry.cs",
+		"public static class QueryRegistry {",
+		`            "DeleteUser",
+            ":exec",
+            "admin.sql",
+            Admin.DELETEUSER_SQL,
+            new Column[] {
+                new Column("id", "int"),
+            },
+            new Column[] {
+            }),`,
+		`            "GetID",
+            ":one",
+            "users.sql",
+            Users.GETID_SQL,`,
+		"    public static Entry? Find(string name) {",
+	)
+	checkFile(t, files, "users.cs", "    internal const string GETID_SQL = ")
+	if registry := files["QueryRegistry.cs"]; strings.Index(registry, `"DeleteUser"`) > strings.Index(registry, `"GetID"`) {
+		t.Error("QueryRegistry.cs does not order the queries by name")
+	}
+}
+
+func TestGenerateTracing(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   string
+		statement bool
+	}{
+		{
+			name:    "npgsql",
+			options: `{"namespace": "App.Db", "emit_tracing": true}`,
+		},
+		{
+			name:      "dapper with the statement",
+			options:   `{"namespace": "App.Db", "driver": "dapper", "emit_tracing": true, "emit_tracing_statement": true}`,
+			statement: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := generateFiles(t, generateRequest(tt.options, "users"))
+			checkFile(t, files, "DbHelper.cs",
+				"using System.Diagnostics;",
+				"public static class SqlcTracing {",
+				`    public static readonly ActivitySource Source = new("App.Db.Sqlc");`,
+			)
+			checkFile(t, files, "users.cs",
+				"using System.Diagnostics;",
+				"using App.Db.helpers;",
+				`        using var activity = SqlcTracing.Source.StartActivity("GetID", ActivityKind.Client);
+        activity?.SetTag("db.system", "postgresql");
+        activity?.SetTag("db.operation", "GetID");`,
+				`        activity?.SetTag("sqlc.command", ":one");`,
+				"            SqlcTracing.RecordException(activity, e);",
+				`            activity?.SetTag("sqlc.rows", rows);`,
+			)
+			statement := `activity?.SetTag("db.statement", GETID_SQL);`
+			if got := strings.Contains(files["users.cs"], statement); got != tt.statement {
+				t.Errorf("users.cs contains %q = %v, want %v", statement, got, tt.statement)
+			}
+		})
+	}
+}
+
+func TestGenerateDiagnostics(t *testing.T) {
+	for _, driver := range []string{core.DriverNpgsql, core.DriverDapper, core.DriverADO} {
+		t.Run(driver, func(t *testing.T) {
+			req := generateRequest(`{"namespace": "App.Db", "emit_diagnostics": true, "driver": "`+driver+`"}`, "users")
+			files := generateFiles(t, req)
+			checkFile(t, files, "DbHelper.cs",
+				"using System.Diagnostics;",
+				"public static class SqlcDiagnostics {",
+				"    public static event Action<QueryExecutingEvent>? OnQueryExecuting;",
+				"    public static event Action<QueryExecutedEvent>? OnQueryExecuted;",
+			)
+			checkFile(t, files, "users.cs",
+				"using App.Db.helpers;",
+				`        var started = SqlcDiagnostics.Executing("GetID", ":one");`,
+				"            error = e;\n            throw;",
+				`            SqlcDiagnostics.Executed("GetID", ":one", started, rows, error);`,
+			)
+		})
+	}
+}
+
+func TestGenerateAwaitOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		lines   []string
+	}{
+		{
+			name:    "npgsql",
+			options: `{"namespace": "App.Db", "emit_async": true, "emit_configure_await": true, "emit_value_task": true}`,
+			lines: []string{
+				"    public static async ValueTask<int?> GetID(this NpgsqlDataSource dbSource,",
+				"    public static async Task<List<int>> ListIDs(this NpgsqlDataSource dbSource,",
+				"        var connection = conn ?? await dbSource.OpenConnectionAsync().ConfigureAwait(false);\n        await using var connectionScope = connection.ConfigureAwait(false);",
+				"        var reader = await command.ExecuteReaderAsync().ConfigureAwait(false);\n        await using var readerScope = reader.ConfigureAwait(false);",
+				"        if (await reader.ReadAsync().ConfigureAwait(false)) {",
+			},
+		},
+		{
+			name:    "dapper",
+			options: `{"namespace": "App.Db", "driver": "dapper", "emit_async": true, "emit_configure_await": true, "emit_value_task": true}`,
+			lines: []string{
+				"    public static async ValueTask<int?> GetID(this IDbConnection conn,",
+				"        return await conn.QuerySingleOrDefaultAsync<int?>(GETID_SQL, null, tx).ConfigureAwait(false);",
+				"    public static async Task<List<int>> ListIDs(this IDbConnection conn,",
+				"        var results = (await conn.QueryAsync<int>(LISTIDS_SQL, null, tx).ConfigureAwait(false)).AsList();",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := generateRequest(tt.options, "users")
+			q := req.Queries[0]
+			req.Queries = append(req.Queries, &plugin.Query{Name: "ListIDs", Cmd: ":many", Filename: q.Filename, Text: q.Text, Columns: q.Columns})
+
+			files := generateFiles(t, req)
+			checkFile(t, files, "users.cs", tt.lines...)
+			if contents := files["users.cs"]; strings.Contains(contents, "await using var connection =") {
+				t.Error("users.cs disposes a local with await using despite emit_configure_await")
+			}
+		})
+	}
+}
+
+func TestGenerateLegacyNpgsql(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		helpers []string
+		queries []string
+	}{
+		{
+			name:    "sync",
+			options: `{"namespace": "App.Db", "npgsql_version": "6.0"}`,
+			helpers: []string{
+				"    public static void RegisterEnumMappings() {\n        NpgsqlConnection.GlobalTypeMapper.MapEnum<Mood>();\n    }",
+				"    public static NpgsqlConnection OpenNpgsqlConnection(string connectionString) {",
+			},
+			queries: []string{
+				"using App.Db.helpers;",
+				"    public static int? GetID(string connectionString, NpgsqlConnection? conn = null, NpgsqlTransaction? tx = null) {",
+				"        using var connection = conn ?? DbHelpers.OpenNpgsqlConnection(connectionString);",
+			},
+		},
+		{
+			name:    "async",
+			options: `{"namespace": "App.Db", "npgsql_version": "6.0.11", "emit_async": true}`,
+			helpers: []string{
+				"    public static async Task<NpgsqlConnection> OpenNpgsqlConnectionAsync(string connectionString) {",
+			},
+			queries: []string{
+				"    public static async Task<int?> GetID(string connectionString, NpgsqlConnection? conn = null, NpgsqlTransaction? tx = null) {",
+				"        await using var connection = conn ?? await DbHelpers.OpenNpgsqlConnectionAsync(connectionString);",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := generateRequest(tt.options, "users")
+			req.Catalog.Schemas[0].Enums = []*plugin.Enum{{Name: "mood", Vals: []string{"happy"}}}
+
+			files := generateFiles(t, req)
+			checkFile(t, files, "DbHelper.cs", tt.helpers...)
+			checkFile(t, files, "users.cs", tt.queries...)
+			for name, contents := range files {
+				if strings.Contains(contents, "NpgsqlDataSource") {
+					t.Errorf("%s refers to NpgsqlDataSource, which Npgsql 6 lacks", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateDependencyInjection(t *testing.T) {
+	req := generateRequest(`{"namespace": "App.Db", "emit_dependency_injection": true, "emit_project_file": true}`, "users")
+	req.Catalog.Schemas[0].Enums = []*plugin.Enum{{Name: "mood", Vals: []string{"happy"}}}
+
+	files := generateFiles(t, req)
+	checkFile(t, files, "DbHelper.cs",
+		"using Microsoft.Extensions.DependencyInjection;",
+		"    public static IServiceCollection AddSqlcQueries(this IServiceCollection services, string connectionString, Action<NpgsqlDataSourceBuilder>? configure = null) {",
+		"            var dbBuilder = new NpgsqlDataSourceBuilder(connectionString).RegisterEnumMappings();\n            configure?.Invoke(dbBuilder);\n            return dbBuilder.Build();",
+		"        services.AddScoped<IQuerier, Querier>();",
+	)
+	checkFile(t, files, "Querier.cs",
+		"public interface IQuerier {",
+		"    public Querier(NpgsqlDataSource dbSource) {",
+	)
+	checkFile(t, files, "App.Db.csproj", `<PackageReference Include="Microsoft.Extensions.DependencyInjection.Abstractions" Version="8.0.2" />`)
+}
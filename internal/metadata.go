@@ -0,0 +1,73 @@
+package csharp
+
+import (
+	"github.com/hyperbeam/sqlc-gen-cs/internal/core"
+)
+
+// schemaMetadataClass is the static class holding the table classes of the
+// schema metadata file.
+const schemaMetadataClass = "SchemaMetadata"
+
+// queryRegistryClass is the static class listing every query.
+const queryRegistryClass = "QueryRegistry"
+
+// querierInterface is the interface of the query methods, and querierClass
+// its implementation running them on the database.
+const (
+	querierInterface = "IQuerier"
+	querierClass     = "Querier"
+)
+
+// fakeQuerierClass is the class faking the queries in tests.
+const fakeQuerierClass = "FakeQuerier"
+
+// fakeQuerierMembers are the members the fake querier declares besides a
+// method and a handler property per query method.
+var fakeQuerierMembers = []string{"Call", "Calls", "CallsTo", "Reset"}
+
+// TableMetadata describes a table in the schema metadata file.
+type TableMetadata struct {
+	// Name is the C# name of the class describing the table.
+	Name    string
+	Schema  string
+	Table   string
+	Columns []ColumnMetadata
+}
+
+// ColumnMetadata names a column constant and its raw database name.
+type ColumnMetadata struct {
+	Name   string
+	DBName string
+}
+
+// SchemaMetadata describes the tables of the models. A table class takes the
+// name of its model unless that clashes with the enclosing class or the
+// members of the table class, and column constants may not be called
+// Columns, the class holding them.
+func SchemaMetadata(classes []core.Class) []TableMetadata {
+	names := map[string]struct{}{
+		schemaMetadataClass: {},
+		"TableName":         {},
+		"Schema":            {},
+		"Columns":           {},
+	}
+
+	tables := make([]TableMetadata, 0, len(classes))
+	for _, c := range classes {
+		t := TableMetadata{
+			Name:   core.UniqueName(c.Name, names),
+			Schema: c.Table.GetSchema(),
+			Table:  c.Table.GetName(),
+		}
+
+		columns := map[string]struct{}{"Columns": {}}
+		for _, m := range c.Members {
+			t.Columns = append(t.Columns, ColumnMetadata{
+				Name:   core.UniqueName(m.Name, columns),
+				DBName: m.DBName,
+			})
+		}
+		tables = append(tables, t)
+	}
+	return tables
+}
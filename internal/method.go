@@ -0,0 +1,213 @@
+package csharp
+
+import (
+	"strconv"
+
+	"github.com/hyperbeam/sqlc-gen-cs/internal/core"
+)
+
+// Method is the data of the templates rendering the method of a query, in
+// its synchronous or asynchronous form.
+type Method struct {
+	core.Query
+	Ctx   *TemplateCtx
+	Async bool
+}
+
+// Method returns the method of q, asynchronous if async is set.
+func (t *TemplateCtx) Method(q core.Query, async bool) Method {
+	return Method{Query: q, Ctx: t, Async: async}
+}
+
+// Methods returns the methods generated for q: the synchronous one, the
+// asynchronous one or both. Commands the drivers do not run, such as
+// :copyfrom, have none.
+func (t *TemplateCtx) Methods(q core.Query) []Method {
+	switch q.Cmd {
+	case ":one", ":many", ":exec", ":execresult", ":execrows":
+	default:
+		return nil
+	}
+
+	var methods []Method
+	if t.EmitSync {
+		methods = append(methods, t.Method(q, false))
+	}
+	if t.EmitAsync {
+		methods = append(methods, t.Method(q, true))
+	}
+	return methods
+}
+
+// AllMethods returns the methods of every query, in query order.
+func (t *TemplateCtx) AllMethods() []Method {
+	var methods []Method
+	for _, q := range t.CodeQueries {
+		methods = append(methods, t.Methods(q)...)
+	}
+	return methods
+}
+
+// UsesHelpers reports whether the query files call into the helpers
+// namespace.
+func (t *TemplateCtx) UsesHelpers() bool {
+	switch {
+	case t.EmitTracing || t.EmitHooks:
+		return true
+	case t.Driver == core.DriverADO:
+		return true
+	default:
+		return t.Driver == core.DriverNpgsql && t.LegacyNpgsql
+	}
+}
+
+// Name returns the name of the method. When both families are generated
+// the asynchronous methods are suffixed with Async. With emit_async alone
+// they keep the query name, as in earlier versions.
+func (m Method) Name() string {
+	if m.Async && m.Ctx.EmitSync {
+		return m.MethodName + "Async"
+	}
+	return m.MethodName
+}
+
+// Call returns the call of the Npgsql method named fn, awaiting its Async
+// variant in asynchronous methods.
+func (m Method) Call(fn string) string {
+	if !m.Async {
+		return fn + "()"
+	}
+	if m.Ctx.ConfigureAwait {
+		return "await " + fn + "Async().ConfigureAwait(false)"
+	}
+	return "await " + fn + "Async()"
+}
+
+// Invoke returns the call of the method named fn with the given type
+// argument, if any, and arguments, awaiting its Async variant in
+// asynchronous methods.
+func (m Method) Invoke(fn, typ, args string) string {
+	if typ != "" {
+		typ = "<" + typ + ">"
+	}
+	if !m.Async {
+		return fn + typ + "(" + args + ")"
+	}
+	if m.Ctx.ConfigureAwait {
+		return "await " + fn + "Async" + typ + "(" + args + ").ConfigureAwait(false)"
+	}
+	return "await " + fn + "Async" + typ + "(" + args + ")"
+}
+
+// DapperArgs returns the arguments of the Dapper call running the query:
+// the SQL, the object binding its parameters, the transaction and the
+// timeout, if any.
+func (m Method) DapperArgs() string {
+	args := m.ConstantName + ", " + m.Arg.BindArgs() + ", tx"
+	if m.Timeout != nil {
+		args += ", commandTimeout: " + strconv.Itoa(*m.Timeout)
+	}
+	return args
+}
+
+// Parameter is a parameter the ado driver adds to the command of a query.
+type Parameter struct {
+	// Value is the C# expression of the value bound.
+	Value string
+	Type  core.DBType
+	// Index numbers the parameter from 1, as $1 in the SQL.
+	Index int
+	Ctx   *TemplateCtx
+}
+
+// Parameters returns the parameters of the query in the order of their
+// positions in the SQL.
+func (m Method) Parameters() []Parameter {
+	if !m.HasArgs() {
+		return nil
+	}
+	if !m.Arg.IsClass() {
+		return []Parameter{{Value: m.Arg.Name, Type: m.Arg.DBType, Index: 1, Ctx: m.Ctx}}
+	}
+
+	members := m.Arg.UniqueMembers()
+	params := make([]Parameter, 0, len(members))
+	for i, member := range members {
+		value := member.ArgName
+		if m.Arg.EmitClass() {
+			value = m.Arg.Name + "." + member.Name
+		}
+		params = append(params, Parameter{Value: value, Type: member.DBType, Index: i + 1, Ctx: m.Ctx})
+	}
+	return params
+}
+
+// Using returns the keywords declaring a disposable local. With
+// configure_await the local is declared plainly and disposed by the
+// declaration Scope adds after it, as await using takes no ConfigureAwait.
+func (m Method) Using() string {
+	switch {
+	case !m.Async:
+		return "using "
+	case m.Ctx.ConfigureAwait:
+		return ""
+	default:
+		return "await using "
+	}
+}
+
+// Scope returns the declaration disposing the local name, if Using left
+// that out.
+func (m Method) Scope(name string) string {
+	if m.Async && m.Ctx.ConfigureAwait {
+		return "\n        await using var " + name + "Scope = " + name + ".ConfigureAwait(false);"
+	}
+	return ""
+}
+
+// Task returns the awaitable type wrapping the result of the method, or ""
+// for a synchronous one. Methods returning a single value may return a
+// ValueTask.
+func (m Method) Task() string {
+	switch {
+	case !m.Async:
+		return ""
+	case m.Ctx.ValueTask && m.Cmd != ":many":
+		return "ValueTask"
+	default:
+		return "Task"
+	}
+}
+
+// ReturnType returns the declared return type of the method.
+func (m Method) ReturnType() string {
+	var typ string
+	switch m.Cmd {
+	case ":one":
+		typ = m.Ret.OneReturnType(m.Ctx.EmitNulls)
+	case ":many":
+		typ = "List<" + m.Ret.EmitReturnType(m.Ctx.EmitNulls) + ">"
+	default:
+		typ = "int"
+	}
+	if task := m.Task(); task != "" {
+		return task + "<" + typ + ">"
+	}
+	return typ
+}
+
+// EmitTracing reports whether the method runs in an Activity.
+func (m Method) EmitTracing() bool {
+	return m.Ctx.EmitTracing
+}
+
+// EmitDiagnostics reports whether the method calls the SqlcDiagnostics hooks.
+func (m Method) EmitDiagnostics() bool {
+	return m.Ctx.EmitHooks
+}
+
+// Observed reports whether the method counts its rows for tracing or the
+// diagnostics hooks.
+func (m Method) Observed() bool {
+	return m.EmitTracing() || m.EmitDiagnostics()
+}
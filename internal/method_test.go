@@ -0,0 +1,35 @@
+package csharp
+
+import (
+	"testing"
+
+	"github.com/hyperbeam/sqlc-gen-cs/internal/core"
+)
+
+func TestMethodName(t *testing.T) {
+	q := core.Query{MethodName: "GetUser", Cmd: ":one"}
+	tests := []struct {
+		name      string
+		sync      bool
+		async     bool
+		wantNames []string
+	}{
+		{name: "sync", sync: true, wantNames: []string{"GetUser"}},
+		{name: "async", async: true, wantNames: []string{"GetUser"}},
+		{name: "both", sync: true, async: true, wantNames: []string{"GetUser", "GetUserAsync"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tctx := &TemplateCtx{EmitSync: tt.sync, EmitAsync: tt.async}
+			methods := tctx.Methods(q)
+			if len(methods) != len(tt.wantNames) {
+				t.Fatalf("got %d methods, want %d", len(methods), len(tt.wantNames))
+			}
+			for i, m := range methods {
+				if got := m.Name(); got != tt.wantNames[i] {
+					t.Errorf("Name() = %q, want %q", got, tt.wantNames[i])
+				}
+			}
+		})
+	}
+}
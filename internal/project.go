@@ -0,0 +1,104 @@
+package csharp
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hyperbeam/sqlc-gen-cs/internal/core"
+)
+
+// Package is a NuGet package referenced by the generated project file.
+type Package struct {
+	Name    string
+	Version string
+}
+
+// defaultPackageVersions are the versions referenced unless overridden by
+// the package_versions option.
+var defaultPackageVersions = map[string]string{
+	"Dapper":           "2.1.35",
+	"Npgsql":           "8.0.5",
+	"Npgsql.NodaTime":  "8.0.5",
+	"System.Text.Json": "8.0.5",
+	"Newtonsoft.Json":  "13.0.3",
+	"Microsoft.Extensions.DependencyInjection.Abstractions": "8.0.2",
+}
+
+// typePackages maps the namespace prefix of a C# type, usually brought in
+// through a type override, to the package declaring it.
+var typePackages = []struct {
+	prefix string
+	pkg    string
+}{
+	{prefix: "NodaTime.", pkg: "Npgsql.NodaTime"},
+	{prefix: "System.Text.Json.", pkg: "System.Text.Json"},
+	{prefix: "Newtonsoft.Json.", pkg: "Newtonsoft.Json"},
+}
+
+// ProjectPackages lists the packages the generated code depends on, sorted
+// by name. Npgsql is referenced unless the ado driver leaves out its
+// features, Dapper with the dapper driver, the dependency injection
+// abstractions with emit_dependency_injection, and other packages only when
+// a member, parameter or result uses one of their types.
+func ProjectPackages(tctx *TemplateCtx, conf *core.Config) []Package {
+	names := map[string]struct{}{}
+	if tctx.NpgsqlFeatures {
+		names["Npgsql"] = struct{}{}
+	}
+	if tctx.Driver == core.DriverDapper {
+		names["Dapper"] = struct{}{}
+	}
+	if tctx.EmitServices {
+		names["Microsoft.Extensions.DependencyInjection.Abstractions"] = struct{}{}
+	}
+	for _, typ := range usedTypes(tctx) {
+		typ = strings.TrimPrefix(typ, "global::")
+		for _, tp := range typePackages {
+			if strings.HasPrefix(typ, tp.prefix) {
+				names[tp.pkg] = struct{}{}
+			}
+		}
+	}
+
+	packages := make([]Package, 0, len(names))
+	for name := range names {
+		version := conf.PackageVersions[name]
+		if version == "" && strings.HasPrefix(name, "Npgsql") {
+			version = conf.NpgsqlVersion
+		}
+		if version == "" {
+			version = defaultPackageVersions[name]
+		}
+		packages = append(packages, Package{Name: name, Version: version})
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	return packages
+}
+
+// usedTypes returns the C# types of every model member, query parameter and
+// query result.
+func usedTypes(tctx *TemplateCtx) []string {
+	var types []string
+	addClass := func(c *core.Class) {
+		for _, m := range c.Members {
+			types = append(types, m.Type)
+		}
+	}
+
+	for i := range tctx.Classes {
+		addClass(&tctx.Classes[i])
+	}
+	for i := range tctx.SharedClasses {
+		addClass(&tctx.SharedClasses[i])
+	}
+	for _, q := range tctx.CodeQueries {
+		for _, v := range []core.QueryValue{q.Arg, q.Ret} {
+			if v.Class != nil {
+				addClass(v.Class)
+			} else if v.Typ != "" {
+				types = append(types, v.Typ)
+			}
+		}
+	}
+	return types
+}